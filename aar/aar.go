@@ -0,0 +1,350 @@
+package aar
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/10Dozen/ts_aar_parser/linereader"
+	"github.com/10Dozen/ts_aar_parser/orbat"
+	"github.com/10Dozen/ts_aar_parser/parseerr"
+)
+
+const (
+	TagUnit    string = "unit"
+	TagVehicle        = "veh"
+	TagAttack         = "av"
+
+	CHECK_CANCEL_EVERY int = 1000
+)
+
+// AAR found in RPT file. Contains AAR metadata and either reference to temporary file with raw AAR lines
+// or, when found by in-memory handler, AAR data converted while RPT was read.
+type AAR struct {
+	Guid    string `json:"guid"`
+	Terrain string `json:"island"`
+	Name    string `json:"name"`
+	Summary string `json:"summary"`
+
+	Excluded  bool   `json:"-"` // AAR will be skipped by ParseAll
+	TimeLabel string `json:"-"` // RPT time label of the AAR header line
+	Date      string `json:"-"` // date of the RPT file AAR was found in
+	Source    string `json:"-"` // name of the RPT file AAR was found in
+	Line      int    `json:"-"` // RPT line number of the AAR header
+
+	ORBAT *orbat.ORBAT `json:"-"` // ORBAT of the same mission, nil if not found, see `LinkORBATs`
+
+	players        []string
+	buff           *bufio.Writer
+	expectedLength int
+	tmp            *os.File
+	converted      *Converted // in-memory data, nil when spooled to `tmp`
+	collecting     bool       // AAR data lines are expected
+}
+
+type Converted struct {
+	Guid      string       `json:"-"` // guid of the source AAR, not a part of exported timeline
+	TimeLabel string       `json:"-"` // RPT time label of the source AAR header line
+	ORBAT     *orbat.ORBAT `json:"-"` // ORBAT of the same mission, nil if not found
+	Metadata  *Metadata    `json:"metadata"`
+	Frames    []*Frame     `json:"timeline"`
+}
+
+type Metadata struct {
+	Terrain  string    `json:"island"`
+	Name     string    `json:"name"`
+	Duration int       `json:"time"`
+	Date     string    `json:"date"`
+	Summary  string    `json:"desc"`
+	Players  []*Player `json:"players"`
+	Objects  *Objects  `json:"objects"`
+}
+
+type Objects struct {
+	Units    []*MetadataUnit    `json:"units"`
+	Vehicles []*MetadataVehicle `json:"vehs"`
+}
+
+// Single second of the AAR. Exported as `[units, vehicles, attacks]`.
+type Frame struct {
+	Units    []*UnitState
+	Vehicles []*VehicleState
+	Attacks  []*Attack
+}
+
+func (f *Frame) MarshalJSON() ([]byte, error) {
+	units, err := json.Marshal(f.Units)
+	if err != nil {
+		return nil, err
+	}
+
+	vehs, err := json.Marshal(f.Vehicles)
+	if err != nil {
+		return nil, err
+	}
+
+	attacks, err := json.Marshal(f.Attacks)
+	if err != nil {
+		return nil, err
+	}
+
+	out := fmt.Sprintf("[%s, %s, %s]", units, vehs, attacks)
+	return []byte(out), nil
+}
+
+// Writes AAR as JSON, the same as `json.Marshal` does, frame by frame,
+// so only a single frame is encoded in memory at once.
+func (c *Converted) EncodeJSON(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	metadata, err := json.Marshal(c.Metadata)
+	if err != nil {
+		return err
+	}
+	bw.WriteString(`{"metadata":`)
+	bw.Write(metadata)
+	bw.WriteString(`,"timeline":`)
+
+	if c.Frames == nil {
+		bw.WriteString("null")
+	} else {
+		bw.WriteByte('[')
+		for i, f := range c.Frames {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			// -- Marshal compacts output of `Frame.MarshalJSON`
+			frame, err := json.Marshal(f)
+			if err != nil {
+				return err
+			}
+			bw.Write(frame)
+		}
+		bw.WriteByte(']')
+	}
+	bw.WriteByte('}')
+	return bw.Flush()
+}
+
+// Parses AAR data stored in temporary file `ts_aar_<guid>_*.tmp` and composes data to `Converted` struct.
+// `Converted` struct is ready to export as JSON. AAR found by in-memory handler is already converted and returned as is.
+// Malformed lines are either returned as skipped lines list or abort parsing, depending on `policy`.
+// Temporary file is removed in any case. Parsing stops with `ctx.Err()` when `ctx` is cancelled.
+func (aar *AAR) Parse(ctx context.Context, policy parseerr.Policy) (*Converted, parseerr.List, error) {
+	if converted := aar.converted; converted != nil {
+		aar.Discard()
+		return aar.complete(converted), nil, nil
+	}
+
+	if aar.tmp == nil {
+		return nil, nil, fmt.Errorf("AAR %s data is already discarded", aar.Guid)
+	}
+
+	converted := aar.newConverted()
+	defer aar.Discard()
+
+	file, err := os.Open(aar.tmp.Name())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var skipped parseerr.List
+	reader := linereader.New(file, 0)
+	for i := 1; ; i++ {
+		if i%CHECK_CANCEL_EVERY == 0 && ctx.Err() != nil {
+			return nil, skipped, ctx.Err()
+		}
+
+		text, _, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, skipped, err
+		}
+
+		// -- Each line is prefixed with it's line number in RPT file
+		numStr, line, _ := strings.Cut(text, " ")
+		num, _ := strconv.Atoi(numStr)
+
+		err = aar.parseLine(num, line, converted)
+		if err = skipped.Handle(err, policy); err != nil {
+			return nil, skipped, err
+		}
+	}
+
+	return aar.complete(converted), skipped, nil
+}
+
+// Creates empty converted AAR with metadata from AAR header.
+func (aar *AAR) newConverted() *Converted {
+	return &Converted{
+		Guid:      aar.Guid,
+		TimeLabel: aar.TimeLabel,
+		Metadata: &Metadata{
+			Terrain:  aar.Terrain,
+			Name:     aar.Name,
+			Duration: 0,
+			Date:     aar.Date,
+			Summary:  aar.Summary,
+			Players:  make([]*Player, 0),
+			Objects: &Objects{
+				Units:    make([]*MetadataUnit, 0),
+				Vehicles: make([]*MetadataVehicle, 0),
+			},
+		},
+		Frames: make([]*Frame, 0, aar.expectedLength/2),
+	}
+}
+
+// Fills metadata known only after all AAR data is read and adds ORBAT group, role and rank to players.
+func (aar *AAR) complete(converted *Converted) *Converted {
+	converted.Metadata.Date = aar.Date
+	converted.Metadata.Duration = len(converted.Frames) - 1
+	converted.ORBAT = aar.ORBAT
+	if aar.ORBAT != nil {
+		for _, p := range converted.Metadata.Players {
+			if u := aar.ORBAT.Unit(p.Name); u != nil {
+				p.Group, p.Role, p.Rank = u.Group(), u.Role, u.Rank
+			}
+		}
+	}
+	return converted
+}
+
+// Removes AAR's temporary file or in-memory data, e.g. when AAR is excluded from conversion.
+func (aar *AAR) Discard() {
+	aar.converted = nil
+	if aar.tmp == nil {
+		return
+	}
+	aar.tmp.Close()
+	os.Remove(aar.tmp.Name())
+	aar.tmp = nil
+}
+
+// Parses single text line and search for objects metadata or frame data.
+// Saves data to `out.Metadata` or `out.Frames`
+func (aar *AAR) parseLine(num int, line string, converted *Converted) error {
+	// -- Check for frame data
+	matches := patterns.Frame.FindStringSubmatch(line)
+	if matches != nil {
+		idx, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return aar.lineError(num, line, fmt.Errorf("%w: %w", parseerr.ErrMalformed, err))
+		}
+		if err := aar.handleFrameData(converted, idx, matches[2], strings.ReplaceAll(matches[3], `""`, `"`)); err != nil {
+			return aar.lineError(num, line, err)
+		}
+		return nil
+	}
+
+	// --- Check for metadata
+	matches = patterns.ObjectMetadata.FindStringSubmatch(line)
+	if matches == nil {
+		return nil
+	}
+	err := aar.handleObjectData(
+		converted,
+		matches[1],
+		strings.TrimSpace(strings.ReplaceAll(matches[3], `""`, `"`)),
+	)
+	if err != nil {
+		return aar.lineError(num, line, err)
+	}
+	return nil
+}
+
+func (aar *AAR) lineError(num int, line string, err error) *parseerr.Error {
+	lineErr := parseerr.New(num, line, err)
+	lineErr.File = aar.Source
+	return lineErr
+}
+
+// Handles object metadata (unit or vehicle) - adds unit/vehice to a list (`out.Metadata.Objects.Units/Vehicles`), saves playable objects into `out.Metadata.Players`
+func (aar *AAR) handleObjectData(converted *Converted, metadataType, content string) error {
+	if metadataType == TagVehicle {
+		vehicle := &MetadataVehicle{}
+		if err := json.Unmarshal([]byte(content), vehicle); err != nil {
+			return fmt.Errorf("%w: %w", parseerr.ErrMalformed, err)
+		}
+		converted.Metadata.Objects.Vehicles = append(
+			converted.Metadata.Objects.Vehicles,
+			vehicle,
+		)
+		return nil
+	}
+
+	unit := &MetadataUnit{}
+	if err := json.Unmarshal([]byte(content), unit); err != nil {
+		return fmt.Errorf("%w: %w", parseerr.ErrMalformed, err)
+	}
+
+	// -- If player and not added already -- add to players meta
+	if unit.IsPlayer == 1 && !slices.ContainsFunc(
+		aar.players,
+		func(e string) bool {
+			return e == unit.Name
+		},
+	) {
+		aar.players = append(aar.players, unit.Name)
+		converted.Metadata.Players = append(
+			converted.Metadata.Players,
+			&Player{Name: unit.Name, Side: unit.Side},
+		)
+	}
+
+	converted.Metadata.Objects.Units = append(
+		converted.Metadata.Objects.Units,
+		unit,
+	)
+	return nil
+}
+
+// Handles frame data and saves to `out.Frames` under given index
+func (aar *AAR) handleFrameData(converted *Converted, idx int, frameType, data string) error {
+	// -- Decode before touching frames, so malformed line doesn't extend timeline
+	var entry any
+	switch frameType {
+	case TagUnit:
+		entry = &UnitState{}
+	case TagVehicle:
+		entry = &VehicleState{}
+	case TagAttack:
+		entry = &Attack{}
+	}
+	if err := json.Unmarshal([]byte(data), entry); err != nil {
+		return fmt.Errorf("%w: %w", parseerr.ErrMalformed, err)
+	}
+
+	// -- Extend Frames, but in case of missing log second - refill with empty frame
+	if len(converted.Frames)-1 < idx {
+		diff := idx - (len(converted.Frames) - 1)
+		for i := 0; i < diff; i++ {
+			converted.Frames = append(converted.Frames, &Frame{
+				Units:    make([]*UnitState, 0),
+				Vehicles: make([]*VehicleState, 0),
+				Attacks:  make([]*Attack, 0),
+			})
+		}
+	}
+
+	// -- Get frame to update
+	frame := converted.Frames[idx]
+
+	switch e := entry.(type) {
+	case *UnitState:
+		frame.Units = append(frame.Units, e)
+	case *VehicleState:
+		frame.Vehicles = append(frame.Vehicles, e)
+	case *Attack:
+		frame.Attacks = append(frame.Attacks, e)
+	}
+	return nil
+}
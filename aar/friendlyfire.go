@@ -0,0 +1,120 @@
+package aar
+
+import "math"
+
+const (
+	FRIENDLY_FIRE_RADIUS float64 = 5 // max distance between attack target and hit unit
+)
+
+// Friendly fire incident: attack that hit a unit of the shooter's side, or a teamkill.
+// Shooter is found the same way as killer, see `Kill`. Hit unit is the alive unit closest to the attack target
+// within `FRIENDLY_FIRE_RADIUS`.
+type FriendlyFire struct {
+	Time      int     `json:"time"` // second of the AAR timeline
+	Teamkill  bool    `json:"teamkill"`
+	Side      string  `json:"side"`
+	ShooterId int     `json:"shooterId"`
+	Shooter   string  `json:"shooter"`
+	TargetId  int     `json:"targetId"`
+	Target    string  `json:"target"`
+	Distance  float64 `json:"distance"`
+	Players   bool    `json:"players"` // both shooter and target are players
+}
+
+// Finds friendly fire incidents in order of time. Hits followed by teamkill of the same target
+// by the same shooter within `KILL_ATTACK_WINDOW` are reported as teamkill only.
+// `kills` are kills of the AAR, see `Kills`.
+func (c *Converted) FriendlyFire(kills []*Kill) []*FriendlyFire {
+	units := make(map[int]*MetadataUnit, len(c.Metadata.Objects.Units))
+	for _, u := range c.Metadata.Objects.Units {
+		units[u.Id] = u
+	}
+	sameSide := func(a, b int) (string, bool) {
+		ua, ub := units[a], units[b]
+		if ua == nil || ub == nil || ua.Side == "" || ua.Side != ub.Side {
+			return "", false
+		}
+		return ua.Side, true
+	}
+	isPlayers := func(a, b int) bool {
+		return units[a].IsPlayer == 1 && units[b].IsPlayer == 1
+	}
+
+	teamkills := make([]*FriendlyFire, 0)
+	for _, kill := range kills {
+		side, ok := sameSide(kill.KillerId, kill.VictimId)
+		if kill.KillerId == NoUnit || !ok {
+			continue
+		}
+		teamkills = append(teamkills, &FriendlyFire{
+			Time:      kill.Time,
+			Teamkill:  true,
+			Side:      side,
+			ShooterId: kill.KillerId,
+			Shooter:   kill.Killer,
+			TargetId:  kill.VictimId,
+			Target:    kill.Victim,
+			Distance:  kill.Distance,
+			Players:   isPlayers(kill.KillerId, kill.VictimId),
+		})
+	}
+	killedBy := func(t, shooterId, targetId int) bool {
+		for _, tk := range teamkills {
+			if tk.ShooterId == shooterId && tk.TargetId == targetId && tk.Time >= t && tk.Time <= t+KILL_ATTACK_WINDOW {
+				return true
+			}
+		}
+		return false
+	}
+
+	incidents := make([]*FriendlyFire, 0)
+	next := 0
+	for t, frame := range c.Frames {
+		for ; next < len(teamkills) && teamkills[next].Time <= t; next++ {
+			incidents = append(incidents, teamkills[next])
+		}
+
+		for _, attack := range frame.Attacks {
+			shooter := c.shooter(t, attack, NoUnit)
+			if shooter == nil {
+				continue
+			}
+			target := c.hitUnit(t, attack, shooter.Id)
+			if target == nil {
+				continue
+			}
+			side, ok := sameSide(shooter.Id, target.Id)
+			if !ok || killedBy(t, shooter.Id, target.Id) {
+				continue
+			}
+			incidents = append(incidents, &FriendlyFire{
+				Time:      t,
+				Side:      side,
+				ShooterId: shooter.Id,
+				Shooter:   units[shooter.Id].Name,
+				TargetId:  target.Id,
+				Target:    units[target.Id].Name,
+				Distance:  round(math.Hypot(target.X-shooter.X, target.Y-shooter.Y)),
+				Players:   isPlayers(shooter.Id, target.Id),
+			})
+		}
+	}
+	return append(incidents, teamkills[next:]...)
+}
+
+// Returns alive unit closest to the target of the attack within `FRIENDLY_FIRE_RADIUS`, except the shooter.
+func (c *Converted) hitUnit(t int, attack *Attack, shooterId int) *UnitState {
+	var (
+		hit     *UnitState
+		closest = FRIENDLY_FIRE_RADIUS
+	)
+	for _, u := range c.Frames[t].Units {
+		if u.Id == shooterId || !u.IsAlive() {
+			continue
+		}
+		if d := math.Hypot(attack.ToX-u.X, attack.ToY-u.Y); d <= closest {
+			hit, closest = u, d
+		}
+	}
+	return hit
+}
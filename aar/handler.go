@@ -0,0 +1,346 @@
+package aar
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/10Dozen/ts_aar_parser/parseerr"
+)
+
+const (
+	FLUSH_AFTER int = 10000
+
+	TMP_FILE_PREFIX  string = "ts_aar_"
+	TMP_FILE_PATTERN string = TMP_FILE_PREFIX + "*.tmp"
+
+	LEGACY_TMP_FILE_PATTERN string = `^([0-9A-Za-z_-]+)\.tmp$` // `<guid>.tmp` of released versions
+	LEGACY_TMP_HEAD_SIZE    int    = 4096                      // bytes read to find AAR tag in legacy temp file
+)
+
+var legacyTmpFileRe = regexp.MustCompile(LEGACY_TMP_FILE_PATTERN)
+
+// Collects AARs from RPT lines. Each AAR's lines are spooled to a temporary file
+// in `tmpDir` and parsed later by `AAR.Parse`. With `InMemory` set, lines are converted as they are read instead,
+// so no temporary files are written at the cost of keeping all found AARs in memory.
+type Handler struct {
+	InMemory bool
+
+	aars     []*AAR
+	tmpDir   string
+	skipping bool // last AAR header was malformed, it's data lines are dropped
+}
+
+// Checks given RPT line for AAR header or AAR data and saves it.
+// Returns `*parseerr.Error` if line is malformed, `num` is line number used in the error.
+// Other errors mean that temporary file can't be written.
+func (ah *Handler) ParseLine(num int, line string) error {
+	// -- Check for AAR line
+	if !patterns.Test.MatchString(line) {
+		return nil
+	}
+
+	// -- Check that line is not cut by Arma
+	if tag := patterns.OpenTag.FindStringSubmatch(line); tag != nil && !strings.Contains(line, "</"+tag[1]+">") {
+		if patterns.TestMeta.MatchString(line) {
+			if err := ah.closeTmpReport(); err != nil {
+				return err
+			}
+			ah.skipping = true
+		}
+		return parseerr.New(num, line, parseerr.ErrTruncated)
+	}
+
+	// -- Check for meta
+	if patterns.TestMeta.MatchString(line) {
+		if err := ah.closeTmpReport(); err != nil {
+			return err
+		}
+
+		matches := patterns.Metadata.FindStringSubmatch(line)
+		if matches == nil {
+			ah.skipping = true
+			return parseerr.New(num, line, parseerr.ErrMalformed)
+		}
+		core := strings.ReplaceAll(strings.Trim(matches[2], " "), `""`, `"`)
+		aar := &AAR{
+			TimeLabel: matches[1],
+			Line:      num,
+		}
+		if err := json.Unmarshal([]byte(core), aar); err != nil {
+			ah.skipping = true
+			return parseerr.New(num, line, fmt.Errorf("%w: %w", parseerr.ErrMalformed, err))
+		}
+
+		ah.skipping = false
+		if ah.InMemory {
+			aar.converted = aar.newConverted()
+		} else if err := ah.createTempReport(aar); err != nil {
+			return err
+		}
+		aar.collecting = true
+		ah.aars = append(ah.aars, aar)
+		return nil
+	}
+
+	return ah.appendToTempReport(num, line)
+}
+
+// Returns AARs found so far.
+func (ah *Handler) AARs() []*AAR {
+	return ah.aars
+}
+
+// Flushes and closes temporary file of the last found AAR. Must be called once all lines are handled.
+func (ah *Handler) Close() error {
+	return ah.closeTmpReport()
+}
+
+// Creates `ts_aar_<guid>_<random>.tmp`, random part keeps the same AAR found in several RPT files apart.
+func (ah *Handler) createTempReport(aar *AAR) error {
+	file, err := os.CreateTemp(ah.tmpDir, fmt.Sprintf("%s%s_*.tmp", TMP_FILE_PREFIX, aar.Guid))
+	if err != nil {
+		return err
+	}
+	aar.buff = bufio.NewWriter(file)
+	aar.tmp = file
+	return nil
+}
+
+func (ah *Handler) appendToTempReport(num int, line string) error {
+	if ah.skipping {
+		return nil
+	}
+	if len(ah.aars) == 0 {
+		return parseerr.New(num, line, parseerr.ErrNoMetadata)
+	}
+	aar := ah.aars[len(ah.aars)-1]
+	if !aar.collecting {
+		return parseerr.New(num, line, parseerr.ErrNoMetadata)
+	}
+
+	aar.expectedLength += 1
+	if aar.converted != nil {
+		return aar.parseLine(num, line, aar.converted)
+	}
+	aar.buff.WriteString(strconv.Itoa(num) + " " + line + "\n")
+	if aar.expectedLength%FLUSH_AFTER == 0 {
+		return aar.buff.Flush()
+	}
+	return nil
+}
+
+func (ah *Handler) closeTmpReport() error {
+	if len(ah.aars) == 0 {
+		return nil
+	}
+
+	aar := ah.aars[len(ah.aars)-1]
+	aar.collecting = false
+	if aar.buff == nil {
+		return nil
+	}
+
+	// Force current buffer to flush
+	err := aar.buff.Flush()
+	aar.buff = nil
+	if closeErr := aar.tmp.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Removes temporary files of all found AARs.
+func (ah *Handler) Clear() {
+	Clear(ah.aars)
+}
+
+// Creates AAR handler that spools AAR lines into temporary files in `tmpDir`.
+func NewHandler(tmpDir string) *Handler {
+	h := &Handler{
+		aars:   make([]*AAR, 0),
+		tmpDir: tmpDir,
+	}
+
+	return h
+}
+
+type parseResult struct {
+	converted *Converted
+	skipped   parseerr.List
+	err       error
+}
+
+// Parses all not excluded AARs in parallel. Excluded AARs are discarded.
+// Returns converted AARs and skipped lines, or first fatal error.
+// On error or `ctx` cancellation temporary files of all given AARs are removed.
+func ParseAll(ctx context.Context, aars []*AAR, policy parseerr.Policy) ([]*Converted, parseerr.List, error) {
+	convertedAARs := make([]*Converted, 0, len(aars))
+	skipped, err := ParseEach(ctx, aars, policy, 0, func(converted *Converted) error {
+		convertedAARs = append(convertedAARs, converted)
+		return nil
+	})
+	if err != nil {
+		return nil, skipped, err
+	}
+	return convertedAARs, skipped, nil
+}
+
+// Parses not excluded AARs with at most `workers` AARs in parallel (0 - no limit) and passes each converted AAR
+// to `handle` in the order of `aars`. Excluded AARs are discarded.
+// AAR converted ahead of the order occupies it's worker until handled, so no more than `workers` converted AARs
+// are kept in memory at once, if `handle` doesn't keep them.
+// Returns skipped lines and first fatal error of parsing or `handle`.
+// On error or `ctx` cancellation temporary files of all given AARs are removed.
+func ParseEach(ctx context.Context, aars []*AAR, policy parseerr.Policy, workers int, handle func(*Converted) error) (parseerr.List, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if workers <= 0 {
+		workers = len(aars)
+	}
+	slots := make(chan struct{}, max(workers, 1))
+
+	// -- Start temp AAR parsing. Slots are taken in order, so AAR that is handled next is always parsed
+	chans := make([]chan parseResult, 0, len(aars))
+	handled := make([]chan struct{}, 0, len(aars))
+	for _, aar := range aars {
+		if aar.Excluded {
+			aar.Discard()
+			continue
+		}
+		chans = append(chans, make(chan parseResult, 1))
+		handled = append(handled, make(chan struct{}))
+	}
+	go func() {
+		i := 0
+		for _, aar := range aars {
+			if aar.Excluded {
+				continue
+			}
+			ch, done := chans[i], handled[i]
+			i++
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				ch <- parseResult{err: ctx.Err()}
+				continue
+			}
+			go func() {
+				// -- Slot is held until converted AAR is handled
+				defer func() { <-slots }()
+				converted, skipped, err := aar.Parse(ctx, policy)
+				ch <- parseResult{converted, skipped, err}
+				<-done
+			}()
+		}
+	}()
+
+	// -- Hand over converted AARs in order
+	var (
+		skipped  parseerr.List
+		firstErr error
+	)
+	for i, ch := range chans {
+		result := <-ch
+		skipped = append(skipped, result.skipped...)
+		if firstErr == nil && result.err != nil {
+			firstErr = result.err
+			cancel()
+		}
+		if firstErr == nil {
+			if err := handle(result.converted); err != nil {
+				firstErr = err
+				cancel()
+			}
+		}
+		close(handled[i])
+	}
+
+	if firstErr != nil {
+		Clear(aars)
+		return skipped, firstErr
+	}
+	return skipped, nil
+}
+
+// Removes temporary files of given AARs.
+func Clear(aars []*AAR) {
+	for _, aar := range aars {
+		aar.Discard()
+	}
+}
+
+// Removes AAR temporary files left in `dir` by crashed runs.
+// Files modified less than `olderThan` ago are kept as they may belong to a running conversion.
+// Returns paths of removed files.
+func SweepTempFiles(dir string, olderThan time.Duration) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, TMP_FILE_PATTERN))
+	if err != nil {
+		return nil, err
+	}
+	return removeStale(paths, olderThan)
+}
+
+// Removes `<guid>.tmp` files left in `dir` by released versions, which kept AAR lines next to the executable.
+// Only files with guid-shaped name which start with AAR line of that guid are removed,
+// other `.tmp` files are never touched. Returns paths of removed files.
+func SweepLegacyTempFiles(dir string, olderThan time.Duration) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0)
+	for _, e := range entries {
+		m := legacyTmpFileRe.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if isLegacyTempFile(path, m[1]) {
+			paths = append(paths, path)
+		}
+	}
+	return removeStale(paths, olderThan)
+}
+
+// Checks that file starts with AAR line of given guid.
+func isLegacyTempFile(path, guid string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	head, err := bufio.NewReader(io.LimitReader(file, int64(LEGACY_TMP_HEAD_SIZE))).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false
+	}
+	return strings.Contains(head, "<AAR-"+guid+">")
+}
+
+// Removes files modified at least `olderThan` ago, returns paths of removed files.
+func removeStale(paths []string, olderThan time.Duration) ([]string, error) {
+	removed := make([]string, 0)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || time.Since(info.ModTime()) < olderThan {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
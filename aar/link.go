@@ -0,0 +1,84 @@
+package aar
+
+import (
+	"strings"
+
+	"github.com/10Dozen/ts_aar_parser/orbat"
+)
+
+// Players of AAR and ORBAT of the mission that don't match each other.
+type ORBATMismatch struct {
+	NotInORBAT []string // AAR players missing in ORBAT
+	NotInAAR   []string // ORBAT units missing in AAR players
+}
+
+// Sets ORBAT of the same mission for each AAR. ORBAT and AAR positions are RPT file name and header line number.
+//
+// ORBAT with the same mission name as AAR name is preferred, the closest one before AAR in the same RPT file,
+// otherwise the latest one. If there is no such ORBAT, the closest ORBAT before AAR in the same RPT file is used,
+// unless another AAR is between them.
+func LinkORBATs(aars []*AAR, orbats []*orbat.ORBAT) {
+	for _, aar := range aars {
+		aar.ORBAT = aar.findORBAT(aars, orbats)
+	}
+}
+
+func (aar *AAR) findORBAT(aars []*AAR, orbats []*orbat.ORBAT) *orbat.ORBAT {
+	var byName, byNameBefore, before *orbat.ORBAT
+	for _, o := range orbats {
+		isBefore := o.Source == aar.Source && o.Line < aar.Line
+		if sameMission(o.Mission, aar.Name) {
+			byName = o
+			if isBefore && (byNameBefore == nil || o.Line > byNameBefore.Line) {
+				byNameBefore = o
+			}
+		}
+		if isBefore && (before == nil || o.Line > before.Line) {
+			before = o
+		}
+	}
+
+	switch {
+	case byNameBefore != nil:
+		return byNameBefore
+	case byName != nil:
+		return byName
+	case before == nil:
+		return nil
+	}
+	for _, other := range aars {
+		if other.Source == aar.Source && other.Line > before.Line && other.Line < aar.Line {
+			return nil
+		}
+	}
+	return before
+}
+
+func sameMission(mission, name string) bool {
+	return strings.EqualFold(strings.TrimSpace(mission), strings.TrimSpace(name))
+}
+
+// Compares AAR players with units of the linked ORBAT. Returns nil if AAR has no ORBAT.
+func (c *Converted) ORBATMismatch() *ORBATMismatch {
+	if c.ORBAT == nil {
+		return nil
+	}
+
+	mismatch := &ORBATMismatch{
+		NotInORBAT: make([]string, 0),
+		NotInAAR:   make([]string, 0),
+	}
+	players := make(map[string]bool, len(c.Metadata.Players))
+	for _, p := range c.Metadata.Players {
+		players[p.Name] = true
+		if c.ORBAT.Unit(p.Name) == nil {
+			mismatch.NotInORBAT = append(mismatch.NotInORBAT, p.Name)
+		}
+	}
+	for _, u := range c.ORBAT.Units() {
+		if !players[u.Name] {
+			mismatch.NotInAAR = append(mismatch.NotInAAR, u.Name)
+		}
+	}
+	return mismatch
+}
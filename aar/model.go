@@ -0,0 +1,236 @@
+package aar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Typed AAR entries. All of them are JSON arrays (tuples) in RPT and in exported timeline.
+// Known leading items are decoded into struct fields, unknown trailing items are kept in `Extra`
+// and written back as is, so conversion stays lossless if the recorder adds new fields.
+
+const (
+	NoVehicle int = -1 // `UnitState.VehicleId` of a unit on foot
+)
+
+// Unit metadata, `[id, name, side, isPlayer]`.
+type MetadataUnit struct {
+	Id       int
+	Name     string
+	Side     string
+	IsPlayer int
+	Extra    []json.RawMessage
+
+	fields int
+}
+
+func (u *MetadataUnit) UnmarshalJSON(buf []byte) (err error) {
+	u.Extra, u.fields, err = unmarshalTuple(buf, 2, &u.Id, &u.Name, &u.Side, &u.IsPlayer)
+	return err
+}
+
+func (u *MetadataUnit) MarshalJSON() ([]byte, error) {
+	return marshalTuple(u.Extra, u.fields, u.Id, u.Name, u.Side, u.IsPlayer)
+}
+
+// Vehicle metadata, `[id, name]`.
+type MetadataVehicle struct {
+	Id    int
+	Name  string
+	Extra []json.RawMessage
+
+	fields int
+}
+
+func (v *MetadataVehicle) UnmarshalJSON(buf []byte) (err error) {
+	v.Extra, v.fields, err = unmarshalTuple(buf, 1, &v.Id, &v.Name)
+	return err
+}
+
+func (v *MetadataVehicle) MarshalJSON() ([]byte, error) {
+	return marshalTuple(v.Extra, v.fields, v.Id, v.Name)
+}
+
+// Player of the AAR, `[name, side]`, or `[name, side, group, role, rank]` when player is found in ORBAT.
+type Player struct {
+	Name  string
+	Side  string
+	Group string
+	Role  string
+	Rank  string
+}
+
+func (p *Player) UnmarshalJSON(buf []byte) error {
+	_, _, err := unmarshalTuple(buf, 2, &p.Name, &p.Side, &p.Group, &p.Role, &p.Rank)
+	return err
+}
+
+func (p *Player) MarshalJSON() ([]byte, error) {
+	if p.Group == "" && p.Role == "" && p.Rank == "" {
+		return json.Marshal([]any{p.Name, p.Side})
+	}
+	return json.Marshal([]any{p.Name, p.Side, p.Group, p.Role, p.Rank})
+}
+
+// Unit state in a frame, `[id, x, y, dir, alive, vehicleId]`.
+type UnitState struct {
+	Id        int
+	X, Y      float64
+	Dir       float64
+	Alive     int
+	VehicleId int // id of the vehicle unit is in or `NoVehicle`
+	Extra     []json.RawMessage
+
+	fields    int
+	aliveBool bool // alive was recorded as bool
+}
+
+func (s *UnitState) UnmarshalJSON(buf []byte) (err error) {
+	s.Alive, s.VehicleId = 1, NoVehicle
+	alive := &boolInt{v: &s.Alive}
+	s.Extra, s.fields, err = unmarshalTuple(buf, 3, &s.Id, &s.X, &s.Y, &s.Dir, alive, &s.VehicleId)
+	s.aliveBool = alive.isBool
+	return err
+}
+
+func (s *UnitState) MarshalJSON() ([]byte, error) {
+	alive := boolInt{v: &s.Alive, isBool: s.aliveBool}
+	return marshalTuple(s.Extra, s.fields, s.Id, s.X, s.Y, s.Dir, alive, s.VehicleId)
+}
+
+func (s *UnitState) IsAlive() bool {
+	return s.Alive > 0
+}
+
+func (s *UnitState) InVehicle() bool {
+	return s.VehicleId != NoVehicle
+}
+
+// Vehicle state in a frame, `[id, x, y, dir, alive]`.
+type VehicleState struct {
+	Id    int
+	X, Y  float64
+	Dir   float64
+	Alive int
+	Extra []json.RawMessage
+
+	fields    int
+	aliveBool bool // alive was recorded as bool
+}
+
+func (s *VehicleState) UnmarshalJSON(buf []byte) (err error) {
+	s.Alive = 1
+	alive := &boolInt{v: &s.Alive}
+	s.Extra, s.fields, err = unmarshalTuple(buf, 3, &s.Id, &s.X, &s.Y, &s.Dir, alive)
+	s.aliveBool = alive.isBool
+	return err
+}
+
+func (s *VehicleState) MarshalJSON() ([]byte, error) {
+	alive := boolInt{v: &s.Alive, isBool: s.aliveBool}
+	return marshalTuple(s.Extra, s.fields, s.Id, s.X, s.Y, s.Dir, alive)
+}
+
+func (s *VehicleState) IsAlive() bool {
+	return s.Alive > 0
+}
+
+// Attack (shot) in a frame, `[fromX, fromY, toX, toY]`.
+type Attack struct {
+	FromX, FromY float64
+	ToX, ToY     float64
+	Extra        []json.RawMessage
+
+	fields int
+}
+
+func (a *Attack) UnmarshalJSON(buf []byte) (err error) {
+	a.Extra, a.fields, err = unmarshalTuple(buf, 4, &a.FromX, &a.FromY, &a.ToX, &a.ToY)
+	return err
+}
+
+func (a *Attack) MarshalJSON() ([]byte, error) {
+	return marshalTuple(a.Extra, a.fields, a.FromX, a.FromY, a.ToX, a.ToY)
+}
+
+// Distance between attack origin and target.
+func (a *Attack) Distance() float64 {
+	return math.Hypot(a.ToX-a.FromX, a.ToY-a.FromY)
+}
+
+// Number item which some recorder versions write as bool, `true` and `false` are decoded as 1 and 0.
+// `isBool` keeps the recorded form, so the item is written back as it was.
+type boolInt struct {
+	v      *int
+	isBool bool
+}
+
+func (b *boolInt) UnmarshalJSON(buf []byte) error {
+	var flag bool
+	if err := json.Unmarshal(buf, &flag); err == nil && string(buf) != "null" {
+		*b.v, b.isBool = 0, true
+		if flag {
+			*b.v = 1
+		}
+		return nil
+	}
+	return json.Unmarshal(buf, b.v)
+}
+
+func (b boolInt) MarshalJSON() ([]byte, error) {
+	if b.isBool {
+		return json.Marshal(*b.v > 0)
+	}
+	return json.Marshal(*b.v)
+}
+
+// Decodes JSON array into `fields` one by one. At least `required` items must be present and decodable.
+// Optional item of unexpected type is not decoded, it's field keeps the default value,
+// and the item is kept with all items after it as extra items, so it's written back as is.
+// Returns items after the decoded fields and number of decoded fields.
+func unmarshalTuple(buf []byte, required int, fields ...any) ([]json.RawMessage, int, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(buf, &items); err != nil {
+		return nil, 0, err
+	}
+	if len(items) < required {
+		return nil, 0, fmt.Errorf("expected at least %d items, got %d", required, len(items))
+	}
+
+	n := min(len(items), len(fields))
+	for i := 0; i < n; i++ {
+		err := json.Unmarshal(items[i], fields[i])
+		if err == nil && string(items[i]) == "null" {
+			// -- Unmarshal ignores null, it would be written back as the default value
+			err = errors.New("unexpected null")
+		}
+		if err != nil {
+			if i < required {
+				return nil, 0, fmt.Errorf("item %d: %w", i, err)
+			}
+			n = i
+			break
+		}
+	}
+
+	var extra []json.RawMessage
+	if len(items) > n {
+		extra = items[n:]
+	}
+	return extra, n, nil
+}
+
+// Encodes first `n` of `fields` followed by `extra` items as JSON array.
+func marshalTuple(extra []json.RawMessage, n int, fields ...any) ([]byte, error) {
+	if n == 0 {
+		n = len(fields)
+	}
+	items := make([]any, 0, n+len(extra))
+	items = append(items, fields[:n]...)
+	for _, e := range extra {
+		items = append(items, e)
+	}
+	return json.Marshal(items)
+}
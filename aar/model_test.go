@@ -0,0 +1,176 @@
+package aar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/10Dozen/ts_aar_parser/parseerr"
+)
+
+func TestTupleRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry json.Unmarshaler
+		in    string
+	}{
+		{"unit", &UnitState{}, `[0,100.5,200,90,1,-1]`},
+		{"unit without optional items", &UnitState{}, `[0,100,200]`},
+		{"unit extra items", &UnitState{}, `[0,100,200,90,1,-1,"new",[1,2]]`},
+		{"unit bool alive", &UnitState{}, `[0,100,200,90,true,-1]`},
+		{"unit bool dead", &UnitState{}, `[0,100,200,90,false,-1]`},
+		{"vehicle bool alive", &VehicleState{}, `[0,300,400,180,true]`},
+		{"unit null alive", &UnitState{}, `[0,100,200,90,null,-1]`},
+		{"vehicle", &VehicleState{}, `[0,300,400,180,1]`},
+		{"vehicle crew", &VehicleState{}, `[0,300,400,180,[1,2],2]`},
+		{"attack", &Attack{}, `[1,2,3,4]`},
+		{"attack extra items", &Attack{}, `[1,2,3,4,{"ammo":"B_556x45"}]`},
+		{"unit metadata", &MetadataUnit{}, `[0,"Alpha","blufor",1]`},
+		{"unit metadata string player flag", &MetadataUnit{}, `[0,"Alpha","blufor","1"]`},
+		{"vehicle metadata", &MetadataVehicle{}, `[0,"Hunter"]`},
+		{"vehicle metadata extra items", &MetadataVehicle{}, `[0,"Hunter","B_MRAP_01_F"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := json.Unmarshal([]byte(tt.in), tt.entry); err != nil {
+				t.Fatalf("Unmarshal(%s): %v", tt.in, err)
+			}
+			out, err := json.Marshal(tt.entry)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(out) != tt.in {
+				t.Errorf("round trip of %s = %s", tt.in, out)
+			}
+		})
+	}
+}
+
+func TestTupleRequiredItems(t *testing.T) {
+	for _, in := range []string{`[0,100]`, `["0",100,200]`, `[null,100,200]`, `{"id":0}`} {
+		if err := json.Unmarshal([]byte(in), &UnitState{}); err == nil {
+			t.Errorf("Unmarshal(%s): expected error", in)
+		}
+	}
+}
+
+func TestTupleBoolAlive(t *testing.T) {
+	tests := []struct {
+		in    string
+		alive int
+	}{
+		{`[3,100,200,90,true,2]`, 1},
+		{`[3,100,200,90,false,2]`, 0},
+		{`[3,100,200,90,0,2]`, 0},
+	}
+	for _, tt := range tests {
+		s := &UnitState{}
+		if err := json.Unmarshal([]byte(tt.in), s); err != nil {
+			t.Fatal(err)
+		}
+		if s.Id != 3 || s.Dir != 90 || s.Alive != tt.alive || s.VehicleId != 2 {
+			t.Errorf("Unmarshal(%s) decoded %+v", tt.in, s)
+		}
+
+		v := &VehicleState{}
+		if err := json.Unmarshal([]byte(tt.in[:len(tt.in)-3]+"]"), v); err != nil {
+			t.Fatal(err)
+		}
+		if v.Alive != tt.alive {
+			t.Errorf("vehicle alive %d, want %d", v.Alive, tt.alive)
+		}
+	}
+}
+
+func TestTupleOptionalMismatchKeepsDefaults(t *testing.T) {
+	s := &UnitState{}
+	if err := json.Unmarshal([]byte(`[3,100,200,90,"dead",2]`), s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Id != 3 || s.Dir != 90 || s.Alive != 1 || s.VehicleId != NoVehicle || len(s.Extra) != 2 {
+		t.Errorf("decoded %+v", s)
+	}
+}
+
+var roundTripRPT = []string{
+	`22:30:00 "<AAR-g1><meta><core>{ ""guid"": ""g1"", ""island"": ""Altis"", ""name"": ""CO10 Test"", ""summary"": ""Test desc"" }</core>"`,
+	`22:30:00 "<AAR-g1><meta><unit>{ ""unitMeta"": [0, ""Alpha"", ""blufor"", 1] }</unit>"`,
+	`22:30:00 "<AAR-g1><meta><unit>{ ""unitMeta"": [1, ""Bravo"", ""opfor"", 0, ""extra""] }</unit>"`,
+	`22:30:00 "<AAR-g1><meta><veh>{ ""vehMeta"": [0, ""Hunter""] }</veh>"`,
+	`22:30:00 "<AAR-g1><0><unit>[0,100,200,90,1,-1]</unit>"`,
+	`22:30:00 "<AAR-g1><0><unit>[1,110.25,200,90,true,-1,""x""]</unit>"`,
+	`22:30:00 "<AAR-g1><0><veh>[0,300,400,180,[0,1],2]</veh>"`,
+	`22:30:02 "<AAR-g1><2><unit>[0,105,203,90,0,0]</unit>"`,
+	`22:30:02 "<AAR-g1><2><av>[100,200,110.5,200,""extra""]</av>"`,
+}
+
+const roundTripTimeline = `[
+	[[[0,100,200,90,1,-1],[1,110.25,200,90,true,-1,"x"]],[[0,300,400,180,[0,1],2]],[]],
+	[[],[],[]],
+	[[[0,105,203,90,0,0]],[],[[100,200,110.5,200,"extra"]]]
+]`
+
+const roundTripObjects = `{
+	"units":[[0,"Alpha","blufor",1],[1,"Bravo","opfor",0,"extra"]],
+	"vehs":[[0,"Hunter"]]
+}`
+
+// RPT lines are converted and exported as timeline JSON the web player reads, entries are written back as they were in RPT.
+func TestConvertedRoundTrip(t *testing.T) {
+	for _, inMemory := range []bool{false, true} {
+		h := NewHandler(t.TempDir())
+		h.InMemory = inMemory
+		for i, line := range roundTripRPT {
+			if err := h.ParseLine(i+1, line); err != nil {
+				t.Fatalf("in memory %v: line %d: %v", inMemory, i+1, err)
+			}
+		}
+		if err := h.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if len(h.AARs()) != 1 {
+			t.Fatalf("in memory %v: found %d AARs", inMemory, len(h.AARs()))
+		}
+
+		converted, skipped, err := h.AARs()[0].Parse(context.Background(), parseerr.Abort)
+		if err != nil || len(skipped) > 0 {
+			t.Fatalf("in memory %v: Parse: %v, skipped %v", inMemory, err, skipped)
+		}
+
+		marshaled, err := json.Marshal(converted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var encoded bytes.Buffer
+		if err := converted.EncodeJSON(&encoded); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(marshaled, encoded.Bytes()) {
+			t.Errorf("in memory %v: EncodeJSON differs from Marshal:\n%s\n%s", inMemory, encoded.Bytes(), marshaled)
+		}
+
+		var exported struct {
+			Metadata struct {
+				Objects json.RawMessage `json:"objects"`
+			} `json:"metadata"`
+			Timeline json.RawMessage `json:"timeline"`
+		}
+		if err := json.Unmarshal(marshaled, &exported); err != nil {
+			t.Fatal(err)
+		}
+		assertJSON(t, "timeline", exported.Timeline, roundTripTimeline)
+		assertJSON(t, "objects", exported.Metadata.Objects, roundTripObjects)
+	}
+}
+
+func assertJSON(t *testing.T, name string, got []byte, want string) {
+	t.Helper()
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(want)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, compact.Bytes()) {
+		t.Errorf("%s:\n got %s\nwant %s", name, got, compact.Bytes())
+	}
+}
@@ -0,0 +1,30 @@
+package aar
+
+import "regexp"
+
+const (
+	TEST_META_PATTERN   string = `<meta><core>`
+	TEST_PATTERN        string = `<AAR-.*>`
+	METADATA_PATTERN    string = `(.*) "<AAR-.*><meta><core>(.*)<\/core>`
+	OBJECT_META_PATTERN string = `<meta><(unit|veh)>\{ ""(unit|veh)Meta"": (.*) \}<\/(unit|veh|av)>`
+	FRAME_PATTERN       string = `<(\d+)><(unit|veh|av)>(.*)<\/(unit|veh|av)>`
+	OPEN_TAG_PATTERN    string = `<AAR-[^>]*><(?:\d+|meta)><(unit|veh|av|core)>`
+)
+
+// Compiled AAR line patterns.
+type Patterns struct {
+	Test, TestMeta, Metadata, ObjectMetadata, Frame, OpenTag *regexp.Regexp
+}
+
+var patterns = NewPatterns()
+
+func NewPatterns() *Patterns {
+	return &Patterns{
+		Test:           regexp.MustCompile(TEST_PATTERN),
+		TestMeta:       regexp.MustCompile(TEST_META_PATTERN),
+		Metadata:       regexp.MustCompile(METADATA_PATTERN),
+		ObjectMetadata: regexp.MustCompile(OBJECT_META_PATTERN),
+		Frame:          regexp.MustCompile(FRAME_PATTERN),
+		OpenTag:        regexp.MustCompile(OPEN_TAG_PATTERN),
+	}
+}
@@ -0,0 +1,215 @@
+package aar
+
+import "math"
+
+const (
+	NoDeath int = -1 // `PlayerStats.DeathTime` of a player that survived
+	NoUnit  int = -1 // `Kill.KillerId` when killer is unknown
+
+	KILL_ATTACK_WINDOW int     = 3  // seconds before death in which the fatal attack is searched
+	KILL_HIT_RADIUS    float64 = 30 // max distance between attack target and victim
+	KILL_FIRE_RADIUS   float64 = 15 // max distance between attack origin and killer
+)
+
+// Mission statistics of a single player. Player may control several units, e.g. after respawn,
+// statistics of all units with the player's name are summed up.
+// Shots are attributed the same way as kills, see `Kill`.
+type PlayerStats struct {
+	Name        string  `json:"name"`
+	Side        string  `json:"side"`
+	Group       string  `json:"group"` // group, role and rank are taken from linked ORBAT, empty if player is not in ORBAT
+	Role        string  `json:"role"`
+	Rank        string  `json:"rank"`
+	TimeAlive   int     `json:"timeAlive"` // seconds
+	DeathTime   int     `json:"deathTime"` // second of the first death, `NoDeath` if survived
+	Deaths      int     `json:"deaths"`
+	Kills       int     `json:"kills"`
+	Shots       int     `json:"shots"`
+	Distance    float64 `json:"distance"`    // travelled while alive
+	VehicleTime int     `json:"vehicleTime"` // seconds spent alive in vehicles
+}
+
+// Calculates statistics of AAR players in order of `Metadata.Players`, `kills` are kills of the AAR, see `Kills`.
+func (c *Converted) PlayerStats(kills []*Kill) []*PlayerStats {
+	stats := make([]*PlayerStats, 0, len(c.Metadata.Players))
+	byName := make(map[string]*PlayerStats, len(c.Metadata.Players))
+	for _, p := range c.Metadata.Players {
+		ps := &PlayerStats{
+			Name:      p.Name,
+			Side:      p.Side,
+			Group:     p.Group,
+			Role:      p.Role,
+			Rank:      p.Rank,
+			DeathTime: NoDeath,
+		}
+		stats = append(stats, ps)
+		byName[p.Name] = ps
+	}
+
+	byUnit := make(map[int]*PlayerStats)
+	for _, u := range c.Metadata.Objects.Units {
+		if ps := byName[u.Name]; ps != nil && u.IsPlayer == 1 {
+			byUnit[u.Id] = ps
+		}
+	}
+
+	last := make(map[int]*UnitState)
+	for t, frame := range c.Frames {
+		for _, state := range frame.Units {
+			prev := last[state.Id]
+			last[state.Id] = state
+
+			ps := byUnit[state.Id]
+			if ps == nil || !state.IsAlive() {
+				continue
+			}
+			ps.TimeAlive++
+			if state.InVehicle() {
+				ps.VehicleTime++
+			}
+			if prev != nil && prev.IsAlive() {
+				ps.Distance += math.Hypot(state.X-prev.X, state.Y-prev.Y)
+			}
+		}
+
+		for _, attack := range frame.Attacks {
+			if shooter := c.shooter(t, attack, NoUnit); shooter != nil && byUnit[shooter.Id] != nil {
+				byUnit[shooter.Id].Shots++
+			}
+		}
+	}
+
+	for _, kill := range kills {
+		if ps := byUnit[kill.VictimId]; ps != nil {
+			ps.Deaths++
+			if ps.DeathTime == NoDeath {
+				ps.DeathTime = kill.Time
+			}
+		}
+		if ps := byUnit[kill.KillerId]; ps != nil {
+			ps.Kills++
+		}
+	}
+
+	for _, ps := range stats {
+		ps.Distance = round(ps.Distance)
+	}
+	return stats
+}
+
+// Death of a unit and it's likely killer, counted in player statistics and friendly fire report.
+// Timeline doesn't tell who fired an attack, so the killer is the unit closest to the origin of the attack
+// that hit near the victim shortly before it's death. When there is no such attack, killer is unknown.
+type Kill struct {
+	Time     int // second of the AAR timeline
+	VictimId int
+	Victim   string
+	KillerId int // `NoUnit` if unknown
+	Killer   string
+	Distance float64 // between killer and victim, or length of the attack if killer is unknown
+}
+
+// Finds kills in AAR timeline in order of time.
+func (c *Converted) Kills() []*Kill {
+	units := make(map[int]*MetadataUnit, len(c.Metadata.Objects.Units))
+	for _, u := range c.Metadata.Objects.Units {
+		units[u.Id] = u
+	}
+
+	kills := make([]*Kill, 0)
+	alive := make(map[int]bool)
+	for t, frame := range c.Frames {
+		for _, state := range frame.Units {
+			wasAlive, seen := alive[state.Id]
+			alive[state.Id] = state.IsAlive()
+			if !seen || !wasAlive || state.IsAlive() {
+				continue
+			}
+
+			kill := &Kill{
+				Time:     t,
+				VictimId: state.Id,
+				KillerId: NoUnit,
+			}
+			if u := units[state.Id]; u != nil {
+				kill.Victim = u.Name
+			}
+
+			killer, distance := c.findKiller(t, state.Id)
+			kill.Distance = round(distance)
+			if killer != nil {
+				kill.KillerId = killer.Id
+				if u := units[killer.Id]; u != nil {
+					kill.Killer = u.Name
+				}
+			}
+			kills = append(kills, kill)
+		}
+	}
+	return kills
+}
+
+// Finds the attack closest to the victim within `KILL_ATTACK_WINDOW` seconds before it's death
+// and the alive unit closest to the origin of that attack.
+// Returns the killer and it's distance to the victim at the time of the attack,
+// or nil and the length of the attack if there is no unit near the origin. Distance is 0 if there is no attack.
+func (c *Converted) findKiller(death, victimId int) (*UnitState, float64) {
+	var (
+		attack  *Attack
+		victim  *UnitState
+		attackT int
+		closest = KILL_HIT_RADIUS
+	)
+	for t := death; t >= max(death-KILL_ATTACK_WINDOW, 0); t-- {
+		state := c.unitState(t, victimId)
+		if state == nil {
+			continue
+		}
+		for _, a := range c.Frames[t].Attacks {
+			if d := math.Hypot(a.ToX-state.X, a.ToY-state.Y); d <= closest {
+				attack, victim, attackT, closest = a, state, t, d
+			}
+		}
+	}
+	if attack == nil {
+		return nil, 0
+	}
+
+	killer := c.shooter(attackT, attack, victimId)
+	if killer == nil {
+		return nil, attack.Distance()
+	}
+	return killer, math.Hypot(victim.X-killer.X, victim.Y-killer.Y)
+}
+
+// Returns alive unit closest to the origin of the attack in the given second, except unit `exceptId`.
+// Returns nil if there is no unit within `KILL_FIRE_RADIUS`.
+func (c *Converted) shooter(t int, attack *Attack, exceptId int) *UnitState {
+	var (
+		shooter *UnitState
+		closest = KILL_FIRE_RADIUS
+	)
+	for _, u := range c.Frames[t].Units {
+		if u.Id == exceptId || !u.IsAlive() {
+			continue
+		}
+		if d := math.Hypot(attack.FromX-u.X, attack.FromY-u.Y); d <= closest {
+			shooter, closest = u, d
+		}
+	}
+	return shooter
+}
+
+// Returns state of the unit in the given second, nil if unit is not in the frame.
+func (c *Converted) unitState(t, id int) *UnitState {
+	for _, u := range c.Frames[t].Units {
+		if u.Id == id {
+			return u
+		}
+	}
+	return nil
+}
+
+func round(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/10Dozen/ts_aar_parser/attendance"
+	"github.com/10Dozen/ts_aar_parser/orbat"
+	"github.com/10Dozen/ts_aar_parser/rpt"
+)
+
+const (
+	ATTENDANCE_REPORT  string = "report"
+	ATTENDANCE_PLAYER         = "player"
+	ATTENDANCE_LEADERS        = "leaders"
+	ATTENDANCE_IMPORT         = "import"
+)
+
+// Runs `attendance` command: reports attendance from the ledger filled on ORBAT export.
+//
+//	attendance [report]      - per-player sessions, missions, streaks and last seen date
+//	attendance player <name> - missions of the player
+//	attendance leaders       - leader slots history
+//	attendance import        - records ORBAT files of ORBATDirectory exported before the ledger existed
+func runAttendance(args []string) int {
+	sub := ATTENDANCE_REPORT
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub, args = args[0], args[1:]
+	}
+	name := ""
+	if sub == ATTENDANCE_PLAYER {
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			fmt.Fprintln(os.Stderr, "Использование: ts_aar_parser attendance player <имя> [флаги]")
+			return EXIT_USAGE
+		}
+		name, args = args[0], args[1:]
+	}
+	switch sub {
+	case ATTENDANCE_REPORT, ATTENDANCE_PLAYER, ATTENDANCE_LEADERS, ATTENDANCE_IMPORT:
+	default:
+		fmt.Fprintf(os.Stderr, "Неизвестная команда attendance %q\n\n%s", sub, usageText)
+		return EXIT_USAGE
+	}
+
+	opts := &CLIOptions{}
+	var asJSON bool
+	fs := flag.NewFlagSet(CMD_ATTENDANCE+" "+sub, flag.ContinueOnError)
+	fs.StringVar(&opts.ConfigFile, "config", defaultConfigFile(), "путь к файлу конфига (env "+ENV_CONFIG+")")
+	fs.StringVar(&opts.Profile, "profile", defaultProfile(), "`имя` профиля конфига (env "+ENV_PROFILE+")")
+	fs.StringVar(&opts.From, "from", "", "учитывать сессии начиная с `даты` (YYYY-MM-DD) включительно")
+	fs.StringVar(&opts.To, "to", "", "учитывать сессии по `дату` (YYYY-MM-DD) включительно")
+	fs.BoolVar(&asJSON, "json", false, "вывести отчет в JSON")
+	fs.Usage = func() {
+		args := sub
+		if sub == ATTENDANCE_PLAYER {
+			args += " <имя>"
+		}
+		fmt.Fprintf(fs.Output(), "Использование: ts_aar_parser %s %s [флаги]\n\nФлаги:\n", CMD_ATTENDANCE, args)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return EXIT_OK
+		}
+		return EXIT_USAGE
+	}
+	// -- Command and player name go before flags, anything left is a mistake, e.g. `attendance --json leaders`
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Лишние аргументы: %s\n\n%s", strings.Join(fs.Args(), " "), usageText)
+		return EXIT_USAGE
+	}
+	if err := (rpt.Selection{From: opts.From, To: opts.To}).Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		return EXIT_USAGE
+	}
+
+	if err := loadConfiguration(opts, false); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		return EXIT_FAILURE
+	}
+
+	if sub == ATTENDANCE_IMPORT {
+		if err := importAttendance(); err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+			return EXIT_FAILURE
+		}
+		return EXIT_OK
+	}
+
+	records, err := attendance.Read(attendancePath(), opts.From, opts.To)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		return EXIT_FAILURE
+	}
+
+	var report any
+	switch sub {
+	case ATTENDANCE_REPORT:
+		report = attendance.Summarize(records)
+	case ATTENDANCE_PLAYER:
+		report = attendance.PlayerHistory(records, name)
+	case ATTENDANCE_LEADERS:
+		report = attendance.LeaderHistory(records)
+	}
+	if asJSON {
+		content, err := json.MarshalIndent(report, "", "    ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+			return EXIT_FAILURE
+		}
+		fmt.Println(string(content))
+		return EXIT_OK
+	}
+
+	switch r := report.(type) {
+	case []*attendance.PlayerSummary:
+		printAttendanceSummary(r, attendance.Sessions(records))
+	case []*attendance.Record:
+		title := fmt.Sprintf("Миссии игрока %s", name)
+		if sub == ATTENDANCE_LEADERS {
+			title = "Командные слоты"
+		}
+		printAttendanceRecords(title, r)
+	}
+	return EXIT_OK
+}
+
+func printAttendanceSummary(summaries []*attendance.PlayerSummary, sessions []string) {
+	if len(sessions) == 0 {
+		fmt.Println("Нет записей о посещаемости.")
+		return
+	}
+	fmt.Printf("Посещаемость %s..%s, сессий: %d\n\n", sessions[0], sessions[len(sessions)-1], len(sessions))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  Игрок\tСессии\tМиссии\tСерия\tЛучшая серия\tПоследний раз\tHQ/SL/TL")
+	for _, ps := range summaries {
+		fmt.Fprintf(
+			w, "  %s\t%d\t%d\t%d\t%d\t%s\t%d/%d/%d\n",
+			ps.Name, ps.Sessions, ps.Missions, ps.CurrentStreak, ps.LongestStreak, ps.LastSeen,
+			ps.LeaderSlots[orbat.SlotHQ], ps.LeaderSlots[orbat.SlotSL], ps.LeaderSlots[orbat.SlotTL],
+		)
+	}
+	w.Flush()
+}
+
+func printAttendanceRecords(title string, records []*attendance.Record) {
+	fmt.Printf("%s: %d\n", title, len(records))
+	if len(records) == 0 {
+		return
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, r := range records {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Date, r.Mission, r.Name, r.Side, r.Group, r.Role, r.Rank)
+	}
+	w.Flush()
+}
+
+// Records ORBAT files of ORBATDirectory. Dates already in the ledger are recorded again,
+// the latest record of a date is used in reports.
+func importAttendance() error {
+	paths, err := attendance.FindORBATFiles(configuration.ORBATDirectory)
+	if err != nil {
+		return err
+	}
+
+	imported := 0
+	for _, path := range paths {
+		records, err := attendance.ReadORBATFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Пропущен файл: %v\n", err)
+			continue
+		}
+		if err := attendance.Append(attendancePath(), records); err != nil {
+			return err
+		}
+		imported++
+	}
+	fmt.Printf("Импортировано ORBAT файлов: %d из %d в %s\n", imported, len(paths), attendancePath())
+	return nil
+}
@@ -0,0 +1,84 @@
+package attendance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var fileDateRE = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+
+// Exported ORBAT file, only fields needed for records.
+type orbatFile struct {
+	Mission string
+	Sides   []struct {
+		Name   string
+		Groups []struct {
+			Name  string
+			Units []struct {
+				Role, Rank, Name string
+			}
+		}
+	}
+}
+
+// Reads records from exported ORBAT file, e.g. `ORBAT.<date>.json`, to add conversions made before the ledger existed.
+// Date is taken from the file name, `Recorded` time is file modification time.
+func ReadORBATFile(path string) ([]*Record, error) {
+	date := fileDateRE.FindString(filepath.Base(path))
+	if date == "" {
+		return nil, fmt.Errorf("%s: no date in file name", filepath.Base(path))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	orbats := make([]*orbatFile, 0)
+	if err := json.Unmarshal(content, &orbats); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	records := make([]*Record, 0)
+	for _, o := range orbats {
+		for _, side := range o.Sides {
+			for _, group := range side.Groups {
+				for _, u := range group.Units {
+					records = append(records, &Record{
+						Name:     u.Name,
+						Date:     date,
+						Mission:  o.Mission,
+						Side:     side.Name,
+						Group:    group.Name,
+						Role:     u.Role,
+						Rank:     u.Rank,
+						Recorded: info.ModTime().UTC(),
+					})
+				}
+			}
+		}
+	}
+	return records, nil
+}
+
+// Returns paths of JSON files in `dir`, not recursive.
+func FindORBATFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0)
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".json") {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,140 @@
+// Package attendance keeps a JSON-lines ledger of players of each converted ORBAT and reports attendance from it.
+package attendance
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/10Dozen/ts_aar_parser/orbat"
+)
+
+// Player of a mission, a single line of the ledger.
+// Records are appended in batches of all missions of a date sharing `Recorded` time, one batch by each ORBAT export.
+// When a date is recorded again, e.g. on re-conversion, only records of the latest batch are used,
+// the same way as ORBAT file of the date is replaced.
+type Record struct {
+	Name     string    `json:"name"`
+	Date     string    `json:"date"`
+	Mission  string    `json:"mission"`
+	Side     string    `json:"side"`
+	Group    string    `json:"group"`
+	Role     string    `json:"role"`
+	Rank     string    `json:"rank"`
+	Recorded time.Time `json:"recorded"`
+}
+
+// Returns records of all units of ORBATs of the date.
+func NewRecords(date string, orbats []*orbat.ORBAT, recorded time.Time) []*Record {
+	records := make([]*Record, 0)
+	for _, o := range orbats {
+		for _, u := range o.Units() {
+			records = append(records, &Record{
+				Name:     u.Name,
+				Date:     date,
+				Mission:  o.Mission,
+				Side:     u.Side(),
+				Group:    u.Group(),
+				Role:     u.Role,
+				Rank:     u.Rank,
+				Recorded: recorded.UTC(),
+			})
+		}
+	}
+	return records
+}
+
+// Leader slot of the record: HQ, SL, TL or empty if player is not a leader.
+func (r *Record) Slot() string {
+	return orbat.LeaderSlot(r.Rank)
+}
+
+// Appends records to the ledger at `path`, the file is created if needed.
+func Append(path string, records []*Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// -- Batch is written at once, so a crash doesn't leave half of the date
+	var content strings.Builder
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		content.Write(line)
+		content.WriteByte('\n')
+	}
+	if _, err := file.WriteString(content.String()); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Reads records of the ledger at `path` dated from `from` to `to` inclusive, empty bound is not checked.
+// Only the latest batch of each date is returned, identical records are returned once.
+// Missing ledger has no records.
+func Read(path, from, to string) ([]*Record, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make([]*Record, 0), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	latest := make(map[string]time.Time)
+	all := make([]*Record, 0)
+
+	scanner := bufio.NewScanner(file)
+	for num := 1; scanner.Scan(); num++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		r := &Record{}
+		if err := json.Unmarshal([]byte(line), r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, num, err)
+		}
+		if (from != "" && r.Date < from) || (to != "" && r.Date > to) {
+			continue
+		}
+
+		if r.Recorded.After(latest[r.Date]) {
+			latest[r.Date] = r.Recorded
+		}
+		all = append(all, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	records := make([]*Record, 0, len(all))
+	seen := make(map[Record]bool, len(all))
+	for _, r := range all {
+		if !r.Recorded.Equal(latest[r.Date]) || seen[*r] {
+			continue
+		}
+		seen[*r] = true
+		records = append(records, r)
+	}
+	slices.SortStableFunc(records, func(a, b *Record) int {
+		return strings.Compare(a.Date, b.Date)
+	})
+	return records, nil
+}
@@ -0,0 +1,119 @@
+package attendance
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/10Dozen/ts_aar_parser/orbat"
+)
+
+// Attendance of a single player. Session is a date with at least one recorded mission.
+type PlayerSummary struct {
+	Name          string         `json:"name"`
+	Sessions      int            `json:"sessions"`      // sessions attended
+	Missions      int            `json:"missions"`      // missions played
+	CurrentStreak int            `json:"currentStreak"` // sessions attended in a row up to the latest session
+	LongestStreak int            `json:"longestStreak"` // longest run of sessions attended in a row
+	FirstSeen     string         `json:"firstSeen"`
+	LastSeen      string         `json:"lastSeen"`
+	LeaderSlots   map[string]int `json:"leaderSlots"` // missions played in HQ, SL and TL slots
+}
+
+// Returns attendance of each player in `records`, most active players first.
+// Streaks count sessions found in `records`, so the result depends on the date range records are read for.
+func Summarize(records []*Record) []*PlayerSummary {
+	sessions := Sessions(records)
+	sessionIdx := make(map[string]int, len(sessions))
+	for i, date := range sessions {
+		sessionIdx[date] = i
+	}
+
+	type missionKey struct{ date, mission string }
+	attended := make(map[string]map[int]bool)
+	missions := make(map[string]map[missionKey]bool)
+	byName := make(map[string]*PlayerSummary)
+	for _, r := range records {
+		ps := byName[r.Name]
+		if ps == nil {
+			ps = &PlayerSummary{
+				Name:        r.Name,
+				FirstSeen:   r.Date,
+				LeaderSlots: make(map[string]int),
+			}
+			byName[r.Name] = ps
+			attended[r.Name] = make(map[int]bool)
+			missions[r.Name] = make(map[missionKey]bool)
+		}
+		ps.FirstSeen = min(ps.FirstSeen, r.Date)
+		ps.LastSeen = max(ps.LastSeen, r.Date)
+		attended[r.Name][sessionIdx[r.Date]] = true
+
+		key := missionKey{r.Date, r.Mission}
+		if missions[r.Name][key] {
+			continue
+		}
+		missions[r.Name][key] = true
+		if slot := r.Slot(); slot != orbat.NoSlot {
+			ps.LeaderSlots[slot]++
+		}
+	}
+
+	summaries := make([]*PlayerSummary, 0, len(byName))
+	for name, ps := range byName {
+		ps.Sessions = len(attended[name])
+		ps.Missions = len(missions[name])
+
+		streak := 0
+		for i := range sessions {
+			if !attended[name][i] {
+				streak = 0
+				continue
+			}
+			streak++
+			ps.LongestStreak = max(ps.LongestStreak, streak)
+		}
+		ps.CurrentStreak = streak
+		summaries = append(summaries, ps)
+	}
+
+	slices.SortFunc(summaries, func(a, b *PlayerSummary) int {
+		return cmp.Or(
+			cmp.Compare(b.Sessions, a.Sessions),
+			cmp.Compare(b.Missions, a.Missions),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+	return summaries
+}
+
+// Returns dates of sessions in `records` in ascending order.
+func Sessions(records []*Record) []string {
+	dates := make([]string, 0)
+	for _, r := range records {
+		dates = append(dates, r.Date)
+	}
+	slices.Sort(dates)
+	return slices.Compact(dates)
+}
+
+// Returns records of the player in order of date.
+func PlayerHistory(records []*Record, name string) []*Record {
+	history := make([]*Record, 0)
+	for _, r := range records {
+		if r.Name == name {
+			history = append(history, r)
+		}
+	}
+	return history
+}
+
+// Returns records of players in leader slots in order of date.
+func LeaderHistory(records []*Record) []*Record {
+	history := make([]*Record, 0)
+	for _, r := range records {
+		if r.Slot() != orbat.NoSlot {
+			history = append(history, r)
+		}
+	}
+	return history
+}
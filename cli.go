@@ -0,0 +1,698 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/10Dozen/ts_aar_parser/aar"
+	"github.com/10Dozen/ts_aar_parser/attendance"
+	"github.com/10Dozen/ts_aar_parser/export"
+	"github.com/10Dozen/ts_aar_parser/orbat"
+	"github.com/10Dozen/ts_aar_parser/parseerr"
+	"github.com/10Dozen/ts_aar_parser/rpt"
+	"github.com/10Dozen/ts_aar_parser/watch"
+)
+
+const (
+	EXIT_OK          int = 0
+	EXIT_FAILURE         = 1
+	EXIT_USAGE           = 2
+	EXIT_INTERRUPTED     = 130
+
+	STALE_TMP_AGE time.Duration = 10 * time.Minute
+
+	STDIN_ARG  string = "-" // RPT file argument to read RPT from stdin
+	STDIN_NAME        = "stdin"
+
+	CMD_CONVERT       string = "convert"
+	CMD_LIST                 = "list"
+	CMD_ORBAT                = "orbat"
+	CMD_AAR                  = "aar"
+	CMD_HELP                 = "help"
+	CMD_REBUILD_INDEX        = "rebuild-index"
+	CMD_WATCH                = "watch"
+	CMD_ATTENDANCE           = "attendance"
+)
+
+const usageText = `Использование: ts_aar_parser [команда] [флаги]
+
+Команды:
+  convert   экспорт ORBAT и AAR из последних RPT файлов (по умолчанию)
+  list      вывод ORBAT и AAR, найденных в последних RPT файлах
+  orbat     экспорт только ORBAT
+  aar       экспорт только AAR
+  watch     отслеживание текущего RPT файла и экспорт AAR и ORBAT сразу после их завершения
+  rebuild-index
+            пересоздание aarListConfig.ini по архивам AAR
+  attendance [report|player <имя>|leaders|import]
+            посещаемость игроков, записанная при экспорте ORBAT
+  help      вывод этой справки
+
+Запуск без аргументов начинает интерактивную конвертацию.
+Флаги команды: 'ts_aar_parser <команда> -h'.
+`
+
+// Options collected from the command line for a single CLI invocation.
+type CLIOptions struct {
+	ConfigFile  string
+	Profile     string
+	AllProfiles bool
+	RptDir      string
+	Out         string
+	OrbatDir    string
+	TmpDir      string
+	InMemory    bool
+	Workers     int
+	MemoryLimit int
+	Exclude     stringList
+	Include     stringList
+	Yes         bool
+	Strict      bool
+	MaxLine     int
+
+	Files        []string // explicit RPT files
+	Date         string
+	From         string
+	To           string
+	SinceLastRun bool
+
+	OrbatOrder   orbat.Order
+	OrbatFormats stringList
+	Duplicates   export.Duplicates
+
+	Poll   time.Duration // watch only
+	Settle time.Duration // watch only
+}
+
+// Repeatable string flag, e.g. `--exclude 1 --exclude 3`.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// Runs CLI with given arguments (without program name) and returns process exit code.
+// Cancelling `ctx` stops conversion.
+func Run(ctx context.Context, args []string) int {
+	// -- No arguments - interactive mode, as when started by double-click
+	if len(args) == 0 {
+		printBanner()
+		return runCommand(ctx, CMD_CONVERT, &CLIOptions{
+			ConfigFile: defaultConfigFile(),
+			Profile:    defaultProfile(),
+			MaxLine:    rpt.DEFAULT_MAX_LINE_LENGTH,
+		})
+	}
+
+	cmd := args[0]
+	if strings.HasPrefix(cmd, "-") {
+		cmd = CMD_CONVERT
+	} else {
+		args = args[1:]
+	}
+
+	switch cmd {
+	case CMD_HELP:
+		fmt.Print(usageText)
+		return EXIT_OK
+	case CMD_REBUILD_INDEX:
+		return runRebuildIndex(args)
+	case CMD_ATTENDANCE:
+		return runAttendance(args)
+	case CMD_CONVERT, CMD_LIST, CMD_ORBAT, CMD_AAR, CMD_WATCH:
+	default:
+		fmt.Fprintf(os.Stderr, "Неизвестная команда %q\n\n%s", cmd, usageText)
+		return EXIT_USAGE
+	}
+
+	opts, err := parseFlags(cmd, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return EXIT_OK
+		}
+		return EXIT_USAGE
+	}
+
+	if cmd == CMD_WATCH {
+		return runWatch(ctx, opts)
+	}
+	return runCommand(ctx, cmd, opts)
+}
+
+func parseFlags(cmd string, args []string) (*CLIOptions, error) {
+	opts := &CLIOptions{}
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.StringVar(&opts.ConfigFile, "config", defaultConfigFile(), "путь к файлу конфига (env "+ENV_CONFIG+")")
+	fs.StringVar(&opts.Profile, "profile", defaultProfile(), "`имя` профиля конфига (env "+ENV_PROFILE+")")
+	fs.StringVar(&opts.RptDir, "rpt-dir", "", "директория с RPT файлами (заменяет RptDirectory и env "+ENV_RPT_DIR+")")
+	fs.StringVar(&opts.Out, "out", "", "директория для экспорта (заменяет AARDirectory и env "+ENV_AAR_DIR+", ORBAT пишется в <out>/orbat)")
+	fs.StringVar(&opts.OrbatDir, "orbat-dir", "", "директория для экспорта ORBAT (заменяет ORBATDirectory и env "+ENV_ORBAT_DIR+")")
+	fs.StringVar(&opts.TmpDir, "tmp-dir", "", "директория для временных файлов AAR (заменяет TmpDirectory и env "+ENV_TMP_DIR+")")
+	fs.BoolVar(&opts.InMemory, "in-memory", false, "конвертировать AAR при чтении RPT, без временных файлов (требует больше памяти)")
+	fs.IntVar(&opts.Workers, "workers", 0, "сколько RPT файлов и AAR обрабатывать одновременно (заменяет Workers, по умолчанию - число CPU)")
+	fs.IntVar(&opts.MemoryLimit, "memory-limit", 0, "мягкий лимит памяти в `МиБ` (заменяет MemoryLimit, 0 - без лимита)")
+	fs.BoolVar(&opts.Strict, "strict", false, "прервать конвертацию на первой повреждённой строке RPT вместо её пропуска")
+	fs.IntVar(&opts.MaxLine, "max-line-length", rpt.DEFAULT_MAX_LINE_LENGTH, "максимальная длина строки RPT в байтах, более длинные строки пропускаются (0 - без лимита)")
+
+	if cmd == CMD_WATCH {
+		// -- Re-read RPT may contain AARs exported before watch was restarted
+		opts.Duplicates = export.DuplicatesSkip
+		fs.DurationVar(&opts.Poll, "poll", watch.DEFAULT_POLL, "как часто проверять директорию RPT на новые строки и файлы")
+		fs.DurationVar(&opts.Settle, "settle", watch.DEFAULT_SETTLE, "AAR или ORBAT экспортируется, если RPT не пишется в течение этого времени")
+	} else {
+		fs.BoolVar(&opts.AllProfiles, "all-profiles", false, "выполнить команду для каждого профиля конфига")
+		fs.StringVar(&opts.Date, "date", "", "конвертировать RPT файлы с датой отчета `YYYY-MM-DD`")
+		fs.StringVar(&opts.From, "from", "", "конвертировать RPT файлы с датой отчета начиная с `YYYY-MM-DD` включительно")
+		fs.StringVar(&opts.To, "to", "", "конвертировать RPT файлы с датой отчета по `YYYY-MM-DD` включительно")
+		fs.BoolVar(&opts.SinceLastRun, "since-last-run", false, "конвертировать RPT файлы, измененные после последней успешной конвертации")
+	}
+
+	if cmd == CMD_CONVERT || cmd == CMD_ORBAT || cmd == CMD_WATCH {
+		fs.Func("orbat-order", "`порядок` сторон, групп и командиров в ORBAT: appearance (по умолчанию) или natural", func(v string) error {
+			order, ok := orbat.ParseOrder(v)
+			if !ok {
+				return fmt.Errorf("неизвестный порядок %q", v)
+			}
+			opts.OrbatOrder = order
+			return nil
+		})
+		fs.Func("orbat-format", "`формат` ORBAT, записываемый рядом с JSON: markdown, bbcode или discord (можно повторять, через запятую)", func(v string) error {
+			for _, name := range strings.Split(v, ",") {
+				if _, ok := export.ParseORBATFormat(strings.TrimSpace(name)); !ok {
+					return fmt.Errorf("неизвестный формат %q", name)
+				}
+			}
+			return opts.OrbatFormats.Set(v)
+		})
+	}
+
+	if cmd == CMD_CONVERT || cmd == CMD_AAR {
+		fs.Var(&opts.Exclude, "exclude", "`guid|номер` AAR, который нужно пропустить (можно повторять, через запятую)")
+		fs.Var(&opts.Include, "include", "`guid|номер` AAR для конвертации, остальные пропускаются (можно повторять, через запятую)")
+		fs.BoolVar(&opts.Yes, "yes", false, "не спрашивать выбор AAR, конвертировать сразу")
+	}
+	if cmd == CMD_CONVERT || cmd == CMD_AAR || cmd == CMD_WATCH {
+		fs.Func("duplicates", "`режим` для AAR, уже экспортированных с тем же guid: replace (по умолчанию, skip для watch), keep или skip", func(v string) error {
+			duplicates, ok := export.ParseDuplicates(v)
+			if !ok {
+				return fmt.Errorf("неизвестный режим %q", v)
+			}
+			opts.Duplicates = duplicates
+			return nil
+		})
+	}
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Использование: ts_aar_parser %s [флаги] [file.rpt ... | -]\n\nФлаги:\n", cmd)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	opts.Files = fs.Args()
+
+	if cmd == CMD_WATCH && len(opts.Files) > 0 {
+		fmt.Fprintln(fs.Output(), "watch отслеживает последний RPT файл в RptDirectory, RPT файлы указывать нельзя")
+		return nil, errors.New("несовместимые аргументы")
+	}
+	// -- Stdin has no file name to take report date from, --date sets it
+	stdin := slices.Contains(opts.Files, STDIN_ARG)
+	if stdin && (len(opts.Files) > 1 || opts.From != "" || opts.To != "" || opts.SinceLastRun) {
+		fmt.Fprintln(fs.Output(), "stdin нельзя совмещать с другими RPT файлами, --from, --to или --since-last-run")
+		return nil, errors.New("несовместимые аргументы")
+	}
+	if !stdin && len(opts.Files) > 0 && (opts.Date != "" || opts.From != "" || opts.To != "" || opts.SinceLastRun) {
+		fmt.Fprintln(fs.Output(), "RPT файлы нельзя совмещать с --date, --from, --to или --since-last-run")
+		return nil, errors.New("несовместимые аргументы")
+	}
+	if stdin {
+		// -- Stdin is busy with RPT content, AAR selection can't be asked
+		opts.Yes = true
+	}
+	if opts.AllProfiles && (opts.RptDir != "" || opts.Out != "" || opts.OrbatDir != "" || len(opts.Files) > 0) {
+		fmt.Fprintln(fs.Output(), "--all-profiles нельзя совмещать с --rpt-dir, --out, --orbat-dir или RPT файлами")
+		return nil, errors.New("несовместимые аргументы")
+	}
+	if opts.Date != "" && (opts.From != "" || opts.To != "") {
+		fmt.Fprintln(fs.Output(), "--date нельзя совмещать с --from или --to")
+		return nil, errors.New("несовместимые аргументы")
+	}
+
+	return opts, nil
+}
+
+func runCommand(ctx context.Context, cmd string, opts *CLIOptions) int {
+	if !opts.AllProfiles {
+		return runProfile(ctx, cmd, opts)
+	}
+
+	profiles, err := listProfiles(opts.ConfigFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		return EXIT_FAILURE
+	}
+
+	// -- Profiles are converted one by one, failed profile doesn't stop the others
+	code := EXIT_OK
+	for _, name := range profiles {
+		fmt.Printf("\n===== Профиль %s =====\n", name)
+		profileOpts := *opts
+		profileOpts.Profile = name
+		profileCode := runProfile(ctx, cmd, &profileOpts)
+		if profileCode == EXIT_INTERRUPTED {
+			return profileCode
+		}
+		code = max(code, profileCode)
+	}
+	return code
+}
+
+func runProfile(ctx context.Context, cmd string, opts *CLIOptions) int {
+	code, err := convert(ctx, cmd, opts)
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		fmt.Fprintln(os.Stderr, "\nКонвертация прервана, временные файлы удалены.")
+		return EXIT_INTERRUPTED
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+	}
+	return code
+}
+
+func convert(ctx context.Context, cmd string, opts *CLIOptions) (int, error) {
+	startedAt := time.Now()
+	if err := loadConfiguration(opts, len(opts.Files) == 0); err != nil {
+		return EXIT_FAILURE, err
+	}
+	if len(opts.Files) == 0 {
+		if err := checkRptDirectory(); err != nil {
+			return EXIT_FAILURE, err
+		}
+	}
+	writesAARs := cmd == CMD_CONVERT || cmd == CMD_AAR
+	writesORBAT := cmd == CMD_CONVERT || cmd == CMD_ORBAT
+	if err := prepareOutputDirectories(writesAARs, writesORBAT); err != nil {
+		return EXIT_FAILURE, err
+	}
+	if !opts.InMemory {
+		if err := prepareTmpDirectory(); err != nil {
+			return EXIT_FAILURE, err
+		}
+	}
+	sweepTempFiles()
+	applyMemoryLimit()
+	memory := startMemoryMonitor()
+	defer memory.Stop()
+
+	policy := parseerr.Skip
+	if opts.Strict {
+		policy = parseerr.Abort
+	}
+
+	files, err := selectRPTs(opts)
+	if err != nil {
+		return EXIT_USAGE, err
+	}
+	if len(files) == 0 && opts.SinceLastRun {
+		fmt.Println("Нет новых RPT файлов с момента последней конвертации.")
+		return EXIT_OK, nil
+	}
+	if len(files) == 0 {
+		return EXIT_FAILURE, fmt.Errorf("не найдено ни одного RPT файла в %s", configuration.RptDirectory)
+	}
+
+	// -- Parse RPT file and gather ORBAT data and AAR metadata for futher selection
+	//    Will also create tmp intemediate files for each AAR that will be used to fully parse AAR if selected.
+	//    These files will be deleted afterward
+	parser := rpt.NewParser(configuration.TmpDirectory)
+	parser.InMemory = opts.InMemory
+	parser.Policy = policy
+	parser.MaxLineLength = opts.MaxLine
+	parser.Workers = configuration.Workers
+
+	dates, groups := rpt.GroupByDate(files)
+	contents := make([]*rpt.Content, 0, len(dates))
+	all := &rpt.Content{}
+	discard := func() {
+		aar.Clear(all.AARs)
+	}
+
+	for _, date := range dates {
+		fmt.Printf("RPT файлы за %s (%d): \n", date, len(groups[date]))
+		for _, v := range groups[date] {
+			fmt.Printf("  - %s\n", v.Name())
+		}
+
+		rptContent, err := parser.ParseFiles(ctx, date, groups[date])
+		if err != nil {
+			discard()
+			return EXIT_FAILURE, err
+		}
+		contents = append(contents, rptContent)
+		all.AARs = append(all.AARs, rptContent.AARs...)
+		all.ORBATs = append(all.ORBATs, rptContent.ORBATs...)
+		all.Skipped = append(all.Skipped, rptContent.Skipped...)
+	}
+	skipped := all.Skipped
+	defer func() {
+		printSkipped(skipped)
+	}()
+
+	switch cmd {
+	case CMD_LIST:
+		printReportContent(all)
+		discard()
+		return EXIT_OK, nil
+	case CMD_ORBAT:
+		discard()
+		for _, rptContent := range contents {
+			if err := exportOrbat(ctx, rptContent, opts.OrbatOrder); err != nil {
+				return EXIT_FAILURE, err
+			}
+		}
+		return EXIT_OK, nil
+	}
+
+	if err := applyAARSelection(all.AARs, opts.Include, opts.Exclude); err != nil {
+		discard()
+		return EXIT_USAGE, err
+	}
+
+	// -- Ask user for excluding some aars if present using AAR metadata
+	if !opts.Yes {
+		if err := handleReportSelection(ctx, all); err != nil {
+			discard()
+			return EXIT_FAILURE, err
+		}
+		fmt.Println()
+	}
+
+	friendlyFire := make([]*export.FriendlyFireReport, 0)
+	orbatLinks := make([]*orbatLink, 0)
+	for _, rptContent := range contents {
+		// -- Export ORBAT
+		if cmd == CMD_CONVERT {
+			if err := exportOrbat(ctx, rptContent, opts.OrbatOrder); err != nil {
+				discard()
+				return EXIT_FAILURE, err
+			}
+		}
+
+		// -- Parse and export AARs one by one, each converted AAR is released once written
+		writer, err := export.NewAARWriter(configuration.AARDirectory, rptContent.Date, opts.Duplicates, configuration.naming)
+		if err != nil {
+			discard()
+			return EXIT_FAILURE, err
+		}
+		aarSkipped, err := aar.ParseEach(ctx, rptContent.AARs, policy, configuration.Workers, func(converted *aar.Converted) error {
+			orbatLinks = append(orbatLinks, newORBATLink(converted))
+			return writer.Write(ctx, converted)
+		})
+		skipped = append(skipped, aarSkipped...)
+		if err != nil {
+			discard()
+			// -- Archives written before the failure are listed, so the next run replaces them instead of making copies
+			if closeErr := writer.Close(); closeErr != nil {
+				fmt.Fprintf(os.Stderr, "Не удалось обновить конфиг AAR: %v\n", closeErr)
+			}
+			return EXIT_FAILURE, err
+		}
+		if err := writer.Close(); err != nil {
+			return EXIT_FAILURE, err
+		}
+		for _, e := range writer.Skipped {
+			fmt.Printf("AAR %s уже экспортирован в %s, пропущен.\n", e.Title, e.Link)
+		}
+		friendlyFire = append(friendlyFire, writer.FriendlyFire...)
+	}
+	fmt.Println("Конфиг AAR обновлен.")
+	printORBATLinks(orbatLinks)
+	printFriendlyFire(friendlyFire)
+	fmt.Printf("Пиковое потребление памяти: %.1f МБ\n", float64(memory.Stop())/(1<<20))
+
+	if savesLastRun(opts) {
+		// -- AARs are already exported, so conversion is still successful
+		if err := saveLastRun(startedAt); err != nil {
+			fmt.Fprintf(os.Stderr, "Не удалось сохранить время конвертации: %v\n", err)
+		}
+	}
+	return EXIT_OK, nil
+}
+
+// Last run time is saved only when RPT files were picked from RptDirectory by the latest date or `--since-last-run`,
+// conversion of explicit files or dates doesn't cover files modified since the last run.
+func savesLastRun(opts *CLIOptions) bool {
+	return len(opts.Files) == 0 && opts.Date == "" && opts.From == "" && opts.To == ""
+}
+
+// Selects RPT files according to CLI options. Without any selection options files of the latest report date are used.
+func selectRPTs(opts *CLIOptions) ([]*rpt.File, error) {
+	if len(opts.Files) > 0 {
+		files := make([]*rpt.File, 0, len(opts.Files))
+		for _, path := range opts.Files {
+			if path == STDIN_ARG {
+				if err := (rpt.Selection{Date: opts.Date}).Validate(); err != nil {
+					return nil, err
+				}
+				file, err := rpt.FromReader(STDIN_NAME, os.Stdin)
+				if err != nil {
+					return nil, err
+				}
+				if opts.Date != "" {
+					file.Date = opts.Date
+				}
+				files = append(files, file)
+				continue
+			}
+
+			found, err := rpt.StatAll(path)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, found...)
+		}
+		return files, nil
+	}
+
+	selection := rpt.Selection{
+		Date: opts.Date,
+		From: opts.From,
+		To:   opts.To,
+	}
+	if err := selection.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.SinceLastRun {
+		lastRun, err := loadLastRun()
+		if err != nil {
+			return nil, err
+		}
+		selection.Since = lastRun
+	}
+
+	files, err := rpt.List(configuration.RptDirectory)
+	if err != nil {
+		return nil, err
+	}
+	if selection == (rpt.Selection{}) && !opts.SinceLastRun {
+		return rpt.Latest(files), nil
+	}
+	return selection.Filter(files), nil
+}
+
+func exportOrbat(ctx context.Context, rptContent *rpt.Content, order orbat.Order) error {
+	for _, o := range rptContent.ORBATs {
+		o.Sort(order)
+	}
+	path, err := export.WriteORBAT(ctx, configuration.ORBATDirectory, rptContent.Date, rptContent.ORBATs, configuration.naming)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("ORBAT экспортирован в %s\n", path)
+	for _, format := range configuration.orbatFormats {
+		textPath, err := export.WriteORBATText(ctx, path, format, rptContent.ORBATs)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("ORBAT экспортирован в %s\n", textPath)
+	}
+
+	// -- Conversion doesn't depend on the ledger, so it's failure is not fatal
+	records := attendance.NewRecords(rptContent.Date, rptContent.ORBATs, time.Now())
+	if err := attendance.Append(attendancePath(), records); err != nil {
+		fmt.Fprintf(os.Stderr, "Не удалось записать посещаемость: %v\n", err)
+	}
+	return nil
+}
+
+// Removes temporary files left by crashed or killed runs, including those of released versions.
+func sweepTempFiles() {
+	removed := make([]string, 0)
+	for _, dir := range slices.Compact([]string{configuration.TmpDirectory, configuration.ExecDirectory}) {
+		aarTmp, err := aar.SweepTempFiles(dir, STALE_TMP_AGE)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Не удалось удалить временные файлы: %v\n", err)
+		}
+		removed = append(removed, aarTmp...)
+	}
+
+	// -- Released versions kept `<guid>.tmp` next to the executable and `aarListConfig.tmp` in the working directory
+	legacyTmp, err := aar.SweepLegacyTempFiles(configuration.ExecDirectory, STALE_TMP_AGE)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Не удалось удалить временные файлы: %v\n", err)
+	}
+	removed = append(removed, legacyTmp...)
+	if wd, err := os.Getwd(); err == nil {
+		cfgTmp, err := export.SweepLegacyConfigTempFile(wd, STALE_TMP_AGE)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Не удалось удалить временные файлы: %v\n", err)
+		}
+		if cfgTmp != "" {
+			removed = append(removed, cfgTmp)
+		}
+	}
+	partial, err := export.SweepTempFiles([]string{
+		configuration.AARDirectory,
+		configuration.ORBATDirectory,
+	}, STALE_TMP_AGE)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Не удалось удалить временные файлы: %v\n", err)
+	}
+	removed = append(removed, partial...)
+
+	for _, path := range removed {
+		fmt.Printf("Удален временный файл прошлого запуска: %s\n", path)
+	}
+}
+
+func printSkipped(skipped parseerr.List) {
+	if len(skipped) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\nПропущено строк с ошибками: %d\n", len(skipped))
+	for _, e := range skipped {
+		fmt.Fprintf(os.Stderr, "  - %v\n", e)
+	}
+}
+
+// ORBAT linked to converted AAR, kept after AAR is released.
+type orbatLink struct {
+	title    string
+	mission  string // empty if AAR has no ORBAT
+	mismatch *aar.ORBATMismatch
+}
+
+func newORBATLink(converted *aar.Converted) *orbatLink {
+	link := &orbatLink{
+		title:    converted.Metadata.Name,
+		mismatch: converted.ORBATMismatch(),
+	}
+	if converted.ORBAT != nil {
+		link.mission = converted.ORBAT.Mission
+	}
+	return link
+}
+
+func printORBATLinks(links []*orbatLink) {
+	if len(links) == 0 {
+		return
+	}
+	fmt.Println("\nСверка AAR и ORBAT:")
+	for _, l := range links {
+		if l.mismatch == nil {
+			fmt.Printf("  %s: ORBAT не найден\n", l.title)
+			continue
+		}
+		if len(l.mismatch.NotInORBAT) == 0 && len(l.mismatch.NotInAAR) == 0 {
+			fmt.Printf("  %s ▸ ORBAT %s: совпадает\n", l.title, l.mission)
+			continue
+		}
+		fmt.Printf("  %s ▸ ORBAT %s:\n", l.title, l.mission)
+		if len(l.mismatch.NotInORBAT) > 0 {
+			fmt.Printf("    нет в ORBAT: %s\n", strings.Join(l.mismatch.NotInORBAT, ", "))
+		}
+		if len(l.mismatch.NotInAAR) > 0 {
+			fmt.Printf("    нет в AAR: %s\n", strings.Join(l.mismatch.NotInAAR, ", "))
+		}
+	}
+}
+
+func printFriendlyFire(reports []*export.FriendlyFireReport) {
+	if len(reports) == 0 {
+		return
+	}
+	fmt.Println("\nОгонь по своим:")
+	for _, r := range reports {
+		fmt.Printf("  %s (%s):\n", r.Title, r.Link)
+		for _, ff := range r.Incidents {
+			kind := "попадание"
+			if ff.Teamkill {
+				kind = "УБИЙСТВО"
+			}
+			fmt.Printf("    %s %s ▸ %s (%s), %s, %.0f м\n", formatMissionTime(ff.Time), ff.Shooter, ff.Target, ff.Side, kind, ff.Distance)
+		}
+	}
+}
+
+// Formats second of AAR timeline as `m:ss` or `h:mm:ss`.
+func formatMissionTime(seconds int) string {
+	h, m, s := seconds/3600, seconds/60%60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// Marks AARs as excluded according to --include/--exclude values.
+// Each value is either AAR's 1-based index (as printed by `list`) or AAR's GUID.
+func applyAARSelection(aars []*aar.AAR, include, exclude []string) error {
+	matches := func(idx int, a *aar.AAR, values []string) bool {
+		for _, v := range values {
+			if v == a.Guid || v == strconv.Itoa(idx+1) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, v := range append(append([]string{}, include...), exclude...) {
+		found := false
+		for idx, a := range aars {
+			if matches(idx, a, []string{v}) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("AAR %q не найден", v)
+		}
+	}
+
+	for idx, a := range aars {
+		if len(include) > 0 && !matches(idx, a, include) {
+			a.Excluded = true
+		}
+		if matches(idx, a, exclude) {
+			a.Excluded = true
+		}
+	}
+	return nil
+}
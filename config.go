@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/10Dozen/ts_aar_parser/export"
+)
+
+// Directories and templates of a single server. Top-level keys of config.json are shared by all profiles.
+type Profile struct {
+	RptDirectory   string
+	AARDirectory   string
+	ORBATDirectory string           // optional, `<AARDirectory>/orbat` by default
+	AttendanceFile string           // optional, attendance ledger in AARDirectory by default
+	Templates      export.Templates // output file name and link templates
+}
+
+type Configuration struct {
+	Profile
+	Profiles map[string]*Profile // named profiles, selected by --profile
+
+	TmpDirectory string   // directory for AAR temporary files, system temp directory by default
+	Workers      int      // max RPT files and AARs parsed at once, number of CPUs by default
+	MemoryLimit  int      // soft memory limit in MiB, 0 - no limit
+	ORBATFormats []string // ORBAT text files written next to the JSON: markdown, bbcode, discord
+
+	ExecDirectory string               `json:"-"` // directory of the executable, set on loading
+	profile       string               // name of the selected profile, empty if none
+	naming        *export.Naming       // compiled `Templates`
+	orbatFormats  []export.ORBATFormat // parsed `ORBATFormats`
+}
+
+const (
+	CONFIG_FILE    string = "config.json"
+	ORBAT_DIR_NAME        = "orbat"
+	ENV_CONFIG            = "TS_AAR_CONFIG"
+	ENV_PROFILE           = "TS_AAR_PROFILE"
+	ENV_RPT_DIR           = "TS_AAR_RPT_DIR"
+	ENV_AAR_DIR           = "TS_AAR_AAR_DIR"
+	ENV_ORBAT_DIR         = "TS_AAR_ORBAT_DIR"
+	ENV_TMP_DIR           = "TS_AAR_TMP_DIR"
+)
+
+var (
+	configuration *Configuration = new(Configuration)
+)
+
+// Returns config file used when --config is not set: $TS_AAR_CONFIG or `config.json`.
+func defaultConfigFile() string {
+	if v := os.Getenv(ENV_CONFIG); v != "" {
+		return v
+	}
+	return CONFIG_FILE
+}
+
+// Returns profile used when --profile is not set: $TS_AAR_PROFILE or none.
+func defaultProfile() string {
+	return os.Getenv(ENV_PROFILE)
+}
+
+// Returns sorted names of profiles defined in config file.
+func listProfiles(filename string) ([]string, error) {
+	if err := getExecutionLocation(); err != nil {
+		return nil, err
+	}
+	if err := readConfig(filename); err != nil {
+		return nil, err
+	}
+	names := slices.Sorted(maps.Keys(configuration.Profiles))
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s: не задано ни одного профиля", filename)
+	}
+	return names, nil
+}
+
+// Loads configuration: reads config file, applies selected profile, env and CLI overrides (in that order),
+// fills defaults and resolves relative directories against the executable directory.
+// RptDirectory is required only by commands that read RPT files, `readsRPT`.
+// Directories are not checked here, see `checkRptDirectory` and `prepareOutputDirectories`.
+func loadConfiguration(opts *CLIOptions, readsRPT bool) error {
+	if err := getExecutionLocation(); err != nil {
+		return err
+	}
+	if err := readConfig(opts.ConfigFile); err != nil {
+		return err
+	}
+	if opts.Profile != "" {
+		if err := applyProfile(opts.Profile); err != nil {
+			return fmt.Errorf("%s: %w", opts.ConfigFile, err)
+		}
+	}
+	applyEnvOverrides()
+	applyCLIOverrides(opts)
+
+	// -- Missing keys
+	missing := make([]string, 0)
+	if readsRPT && configuration.RptDirectory == "" {
+		missing = append(missing, fmt.Sprintf("RptDirectory (или %s, --rpt-dir)", ENV_RPT_DIR))
+	}
+	if configuration.AARDirectory == "" {
+		missing = append(missing, fmt.Sprintf("AARDirectory (или %s, --out)", ENV_AAR_DIR))
+	}
+	if len(missing) > 0 && len(configuration.Profiles) > 0 && opts.Profile == "" {
+		return fmt.Errorf("%s: не задано %s; выберите профиль через --profile", opts.ConfigFile, strings.Join(missing, ", "))
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s: не задано %s", opts.ConfigFile, strings.Join(missing, ", "))
+	}
+	if configuration.Workers < 0 {
+		return fmt.Errorf("%s: Workers не может быть отрицательным", opts.ConfigFile)
+	}
+	if configuration.MemoryLimit < 0 {
+		return fmt.Errorf("%s: MemoryLimit не может быть отрицательным", opts.ConfigFile)
+	}
+	if configuration.Workers == 0 {
+		configuration.Workers = runtime.NumCPU()
+	}
+
+	configuration.RptDirectory = resolvePath(configuration.RptDirectory)
+	configuration.AARDirectory = resolvePath(configuration.AARDirectory)
+	if configuration.ORBATDirectory == "" {
+		configuration.ORBATDirectory = filepath.Join(configuration.AARDirectory, ORBAT_DIR_NAME)
+	}
+	configuration.ORBATDirectory = resolvePath(configuration.ORBATDirectory)
+	if configuration.TmpDirectory == "" {
+		configuration.TmpDirectory = os.TempDir()
+	}
+	configuration.TmpDirectory = resolvePath(configuration.TmpDirectory)
+	configuration.AttendanceFile = resolvePath(configuration.AttendanceFile)
+
+	configuration.orbatFormats = make([]export.ORBATFormat, 0, len(configuration.ORBATFormats))
+	for _, name := range configuration.ORBATFormats {
+		format, ok := export.ParseORBATFormat(name)
+		if !ok {
+			return fmt.Errorf("%s: неизвестный формат ORBAT %q", opts.ConfigFile, name)
+		}
+		if !slices.Contains(configuration.orbatFormats, format) {
+			configuration.orbatFormats = append(configuration.orbatFormats, format)
+		}
+	}
+
+	naming, err := export.NewNaming(configuration.Templates)
+	if err != nil {
+		return fmt.Errorf("%s: %w", opts.ConfigFile, err)
+	}
+	configuration.naming = naming
+	return nil
+}
+
+func getExecutionLocation() error {
+	ex, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	configuration.ExecDirectory = filepath.Dir(ex)
+	return nil
+}
+
+// Reads config file. Relative path is looked up in the working directory, then next to the executable.
+// Unknown keys are reported as errors.
+func readConfig(filename string) error {
+	path := filename
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) && !filepath.IsAbs(path) {
+		path = filepath.Join(configuration.ExecDirectory, filename)
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("файл конфига %s не найден (укажите путь через --config или %s)", filename, ENV_CONFIG)
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	*configuration = Configuration{ExecDirectory: configuration.ExecDirectory}
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(configuration); err != nil {
+		return fmt.Errorf("не удалось прочитать %s: %w", path, err)
+	}
+	return nil
+}
+
+// Applies non-empty fields of the named profile over shared settings.
+// Profile with its own AARDirectory doesn't inherit shared ORBATDirectory.
+func applyProfile(name string) error {
+	p := configuration.Profiles[name]
+	if p == nil {
+		names := slices.Sorted(maps.Keys(configuration.Profiles))
+		return fmt.Errorf("неизвестный профиль %q, доступны: %s", name, strings.Join(names, ", "))
+	}
+
+	if p.RptDirectory != "" {
+		configuration.RptDirectory = p.RptDirectory
+	}
+	if p.AARDirectory != "" {
+		configuration.AARDirectory = p.AARDirectory
+		configuration.ORBATDirectory = ""
+	}
+	if p.ORBATDirectory != "" {
+		configuration.ORBATDirectory = p.ORBATDirectory
+	}
+	if p.AttendanceFile != "" {
+		configuration.AttendanceFile = p.AttendanceFile
+	}
+
+	t := &configuration.Templates
+	for field, value := range map[*string]string{
+		&t.AARDir:        p.Templates.AARDir,
+		&t.AARFilename:   p.Templates.AARFilename,
+		&t.AARLink:       p.Templates.AARLink,
+		&t.ORBATFilename: p.Templates.ORBATFilename,
+	} {
+		if value != "" {
+			*field = value
+		}
+	}
+
+	configuration.profile = name
+	return nil
+}
+
+func applyEnvOverrides() {
+	for env, field := range map[string]*string{
+		ENV_RPT_DIR:   &configuration.RptDirectory,
+		ENV_AAR_DIR:   &configuration.AARDirectory,
+		ENV_ORBAT_DIR: &configuration.ORBATDirectory,
+		ENV_TMP_DIR:   &configuration.TmpDirectory,
+	} {
+		if v := os.Getenv(env); v != "" {
+			*field = v
+		}
+	}
+}
+
+func applyCLIOverrides(opts *CLIOptions) {
+	if opts.RptDir != "" {
+		configuration.RptDirectory = opts.RptDir
+	}
+	if opts.Out != "" {
+		configuration.AARDirectory = opts.Out
+		configuration.ORBATDirectory = filepath.Join(opts.Out, ORBAT_DIR_NAME)
+	}
+	if opts.OrbatDir != "" {
+		configuration.ORBATDirectory = opts.OrbatDir
+	}
+	if opts.TmpDir != "" {
+		configuration.TmpDirectory = opts.TmpDir
+	}
+	if opts.Workers != 0 {
+		configuration.Workers = opts.Workers
+	}
+	if opts.MemoryLimit != 0 {
+		configuration.MemoryLimit = opts.MemoryLimit
+	}
+	if len(opts.OrbatFormats) > 0 {
+		configuration.ORBATFormats = opts.OrbatFormats
+	}
+}
+
+// Resolves path relative to the executable directory.
+func resolvePath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(configuration.ExecDirectory, path)
+}
+
+// Checks that RPT directory exists.
+func checkRptDirectory() error {
+	return checkDirectory("RptDirectory", configuration.RptDirectory)
+}
+
+// Checks that output directories exist and are writable.
+// AAR directory must exist, directory for AAR archives (`aars/` by default) and ORBAT directory are created if needed.
+func prepareOutputDirectories(aars, orbats bool) error {
+	if aars {
+		if err := checkDirectory("AARDirectory", configuration.AARDirectory); err != nil {
+			return err
+		}
+		root := filepath.Join(configuration.AARDirectory, configuration.naming.AARRoot())
+		if err := prepareDirectory("AARDirectory", root); err != nil {
+			return err
+		}
+	}
+	if orbats {
+		if err := prepareDirectory("ORBATDirectory", configuration.ORBATDirectory); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Creates directory for AAR temporary files. Not needed when AARs are converted in memory.
+func prepareTmpDirectory() error {
+	return prepareDirectory("TmpDirectory", configuration.TmpDirectory)
+}
+
+func checkDirectory(key, dir string) error {
+	info, err := os.Stat(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s %s не существует", key, dir)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s %s не является директорией", key, dir)
+	}
+	return nil
+}
+
+// Creates directory if needed and checks that files can be created in it.
+func prepareDirectory(key, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	probe, err := os.CreateTemp(dir, ".write_check_*")
+	if err != nil {
+		return fmt.Errorf("%s %s недоступна для записи: %w", key, dir, err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
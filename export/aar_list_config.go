@@ -0,0 +1,190 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	AAR_CONFIG_TMP_FILENAME string = "aarListConfig.tmp"
+	AAR_CONFIG_HEADER              = "aarConfig = [\n"
+	AAR_CONFIG_FOOTER              = "];\n"
+	AAR_CONFIG_BACKUPS      int    = 5
+)
+
+// Entry of `aarListConfig.ini` used by the web player to list available AARs.
+type AARConfigEntry struct {
+	Date    string `json:"date"`
+	Title   string `json:"title"`
+	Terrain string `json:"terrain"`
+	Link    string `json:"link"`
+	Guid    string `json:"guid,omitempty"` // guid of the AAR, missing in entries of older versions
+}
+
+func NewAARConfigEntry(date, title, terrain, link, guid string) *AARConfigEntry {
+	return &AARConfigEntry{
+		Date:    date,
+		Title:   title,
+		Terrain: terrain,
+		Link:    link,
+		Guid:    guid,
+	}
+}
+
+// Key identifying the entry in AAR list config. Older versions listed different missions with the same link,
+// so link alone doesn't identify the entry.
+func (e *AARConfigEntry) Key() string {
+	return strings.Join([]string{e.Date, e.Title, e.Terrain, e.Link}, "\x00")
+}
+
+// Reads entries of the AAR list config. Missing file is treated as an empty config.
+// Hand-edited configs with trailing commas and `];` ending are accepted.
+func ReadAARListConfig(cfgPath string) ([]*AARConfigEntry, error) {
+	content, err := os.ReadFile(cfgPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return make([]*AARConfigEntry, 0), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseAARListConfig(content)
+}
+
+// Parses content of the AAR list config: `aarConfig = [ {...}, ... ];`.
+func ParseAARListConfig(content []byte) ([]*AARConfigEntry, error) {
+	start := bytes.IndexByte(content, '[')
+	end := bytes.LastIndexByte(content, ']')
+	if start == -1 || end < start {
+		if len(bytes.TrimSpace(content)) == 0 {
+			return make([]*AARConfigEntry, 0), nil
+		}
+		return nil, errors.New("AAR list config has no entries list")
+	}
+
+	entries := make([]*AARConfigEntry, 0)
+	if err := json.Unmarshal(stripTrailingCommas(content[start:end+1]), &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse AAR list config: %w", err)
+	}
+	return entries, nil
+}
+
+// Merges new entries into existing ones. Entry with the same key is updated in place,
+// other new entries are prepended in given order. Duplicates already present in the config are dropped.
+func MergeAARListConfig(existing, entries []*AARConfigEntry) []*AARConfigEntry {
+	updates := make(map[string]*AARConfigEntry, len(entries))
+	for _, e := range entries {
+		updates[e.Key()] = e
+	}
+
+	merged := make([]*AARConfigEntry, 0, len(existing)+len(entries))
+	seen := make(map[string]bool, len(existing)+len(entries))
+	for _, e := range existing {
+		if upd, ok := updates[e.Key()]; ok {
+			e = upd
+		}
+		if seen[e.Key()] {
+			continue
+		}
+		seen[e.Key()] = true
+		merged = append(merged, e)
+	}
+
+	prepend := make([]*AARConfigEntry, 0, len(entries))
+	for _, e := range entries {
+		if seen[e.Key()] {
+			continue
+		}
+		seen[e.Key()] = true
+		prepend = append(prepend, e)
+	}
+
+	return append(prepend, merged...)
+}
+
+// Writes AAR list config from entries.
+func FormatAARListConfig(entries []*AARConfigEntry) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(AAR_CONFIG_HEADER)
+
+	for i, entry := range entries {
+		out, err := json.MarshalIndent(entry, "    ", "    ")
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("    ")
+		buf.Write(out)
+		if i < len(entries)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString(AAR_CONFIG_FOOTER)
+	return buf.Bytes(), nil
+}
+
+// Adds given entries to the AAR list config at `cfgPath`: existing entries are updated, new ones are prepended.
+// Config is rewritten from parsed entries, so duplicates are removed as well.
+func UpdateAARListConfig(cfgPath string, entries []*AARConfigEntry) error {
+	// -- Read config
+	existing, err := ReadAARListConfig(cfgPath)
+	if err != nil {
+		return err
+	}
+	return WriteAARListConfig(cfgPath, MergeAARListConfig(existing, entries))
+}
+
+// Writes AAR list config from given entries, replacing existing one.
+// Previous config is kept as backup, see `RotateBackups`.
+func WriteAARListConfig(cfgPath string, entries []*AARConfigEntry) error {
+	content, err := FormatAARListConfig(entries)
+	if err != nil {
+		return err
+	}
+
+	if err := RotateBackups(cfgPath, AAR_CONFIG_BACKUPS); err != nil {
+		return fmt.Errorf("failed to backup %s: %w", cfgPath, err)
+	}
+	return WriteFileAtomic(cfgPath, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
+}
+
+// Removes commas followed by `]` or `}`, skipping string literals.
+func stripTrailingCommas(content []byte) []byte {
+	out := make([]byte, 0, len(content))
+	inString, escaped := false, false
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			out = append(out, c)
+			continue
+		}
+
+		if c == '"' {
+			inString = true
+		}
+		if c == ',' {
+			next := bytes.TrimLeft(content[i+1:], " \t\r\n")
+			if len(next) > 0 && (next[0] == ']' || next[0] == '}') {
+				continue
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
@@ -0,0 +1,145 @@
+package export
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStripTrailingCommas(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"no commas", `[{"a":1}]`, `[{"a":1}]`},
+		{"list", `[{"a":1},]`, `[{"a":1}]`},
+		{"object", `[{"a":1,}]`, `[{"a":1}]`},
+		{"whitespace before closing", "[{\"a\":1},\r\n\t ]", "[{\"a\":1}\r\n\t ]"},
+		{"separating comma kept", `[{"a":1}, {"a":2}]`, `[{"a":1}, {"a":2}]`},
+		{"comma in string before bracket", `[{"a":"x,]"}]`, `[{"a":"x,]"}]`},
+		{"comma in string before brace", `[{"a":"x, }"},]`, `[{"a":"x, }"}]`},
+		{"escaped quote in string", `[{"a":"say \",]\" here",}]`, `[{"a":"say \",]\" here"}]`},
+		{"escaped backslash ends string", `[{"a":"dir\\",}]`, `[{"a":"dir\\"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(stripTrailingCommas([]byte(tt.in))); got != tt.want {
+				t.Errorf("stripTrailingCommas(%s) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAARListConfig(t *testing.T) {
+	entry := func(title, link string) *AARConfigEntry {
+		return NewAARConfigEntry("2024-11-22", title, "Altis", link, "")
+	}
+	tests := []struct {
+		name    string
+		in      string
+		want    []*AARConfigEntry
+		wantErr bool
+	}{
+		{
+			name: "written by the tool",
+			in:   "aarConfig = [\n    {\"date\": \"2024-11-22\", \"title\": \"A\", \"terrain\": \"Altis\", \"link\": \"aars/a.zip\"}\n];\n",
+			want: []*AARConfigEntry{entry("A", "aars/a.zip")},
+		},
+		{
+			name: "hand-edited with trailing commas",
+			in: `aarConfig = [
+				{"date": "2024-11-22", "title": "A", "terrain": "Altis", "link": "aars/a.zip",},
+				{"date": "2024-11-22", "title": "B", "terrain": "Altis", "link": "aars/b.zip"},
+			];`,
+			want: []*AARConfigEntry{entry("A", "aars/a.zip"), entry("B", "aars/b.zip")},
+		},
+		{
+			name: "without semicolon",
+			in:   `aarConfig = [{"date": "2024-11-22", "title": "A", "terrain": "Altis", "link": "aars/a.zip"}]`,
+			want: []*AARConfigEntry{entry("A", "aars/a.zip")},
+		},
+		{
+			name: "brackets and commas in title",
+			in:   `aarConfig = [{"date": "2024-11-22", "title": "Op [Night], ]", "terrain": "Altis", "link": "aars/a.zip",},];`,
+			want: []*AARConfigEntry{entry("Op [Night], ]", "aars/a.zip")},
+		},
+		{
+			name: "guid",
+			in:   `aarConfig = [{"date": "2024-11-22", "title": "A", "terrain": "Altis", "link": "aars/a.zip", "guid": "g1"}];`,
+			want: []*AARConfigEntry{NewAARConfigEntry("2024-11-22", "A", "Altis", "aars/a.zip", "g1")},
+		},
+		{name: "empty list", in: "aarConfig = [\n];\n", want: []*AARConfigEntry{}},
+		{name: "empty file", in: " \n", want: []*AARConfigEntry{}},
+		{name: "no list", in: "aarConfig = ;", wantErr: true},
+		{name: "broken entry", in: `aarConfig = [{"date": 2024-11-22}];`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAARListConfig([]byte(tt.in))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeAARListConfig(t *testing.T) {
+	e := func(title, link string) *AARConfigEntry {
+		return NewAARConfigEntry("2024-11-22", title, "Altis", link, "")
+	}
+	a, b, c := e("A", "aars/a.zip"), e("B", "aars/b.zip"), e("C", "aars/c.zip")
+	a2 := NewAARConfigEntry("2024-11-22", "A", "Altis", "aars/a.zip", "g1")
+	d, f := e("D", "aars/d.zip"), e("F", "aars/f.zip")
+	// -- Older versions listed different missions with the same link
+	shared1 := NewAARConfigEntry("2021-01-27", "CO18 King of the Hill", "sahrani", "aars/shared.zip", "")
+	shared2 := NewAARConfigEntry("2017-04-23", "CO34 Beaten Dog", "sahrani", "aars/shared.zip", "")
+	shared3 := NewAARConfigEntry("2017-04-23", "CO34 Beaten Dog", "sahrani", "aars/shared.zip", "g2")
+
+	tests := []struct {
+		name              string
+		existing, entries []*AARConfigEntry
+		want              []*AARConfigEntry
+	}{
+		{"prepend to empty", nil, []*AARConfigEntry{d, f}, []*AARConfigEntry{d, f}},
+		{"prepend in order", []*AARConfigEntry{a, b}, []*AARConfigEntry{d, f}, []*AARConfigEntry{d, f, a, b}},
+		{"update in place", []*AARConfigEntry{c, a, b}, []*AARConfigEntry{a2}, []*AARConfigEntry{c, a2, b}},
+		{"update and prepend", []*AARConfigEntry{a, b}, []*AARConfigEntry{d, a2}, []*AARConfigEntry{d, a2, b}},
+		{"existing duplicates dropped", []*AARConfigEntry{a, b, a}, nil, []*AARConfigEntry{a, b}},
+		{"new duplicates dropped", []*AARConfigEntry{b}, []*AARConfigEntry{d, d}, []*AARConfigEntry{d, b}},
+		{"shared link kept", []*AARConfigEntry{shared1, shared2}, nil, []*AARConfigEntry{shared1, shared2}},
+		{"shared link updated", []*AARConfigEntry{shared1, shared2}, []*AARConfigEntry{shared3}, []*AARConfigEntry{shared1, shared3}},
+		{"shared link prepended", []*AARConfigEntry{shared2}, []*AARConfigEntry{shared1}, []*AARConfigEntry{shared1, shared2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeAARListConfig(tt.existing, tt.entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatAARListConfigRoundTrip(t *testing.T) {
+	entries := []*AARConfigEntry{
+		NewAARConfigEntry("2024-11-22", `Op "Dawn", ]`, "Altis", "aars/a.zip", "g1"),
+		NewAARConfigEntry("2024-11-21", "B", "Stratis", "aars/b.zip", ""),
+	}
+	content, err := FormatAARListConfig(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseAARListConfig(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("got %v, want %v", got, entries)
+	}
+}
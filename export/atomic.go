@@ -0,0 +1,143 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	PARTIAL_SUFFIX string = ".partial"
+	BACKUP_SUFFIX         = ".bak"
+)
+
+// Writes file at `path` atomically: content is written by `write` into temporary file
+// in the same directory, synced to disk and renamed into place.
+// On any error temporary file is removed and existing file at `path` is left untouched.
+func WriteFileAtomic(path string, write func(w io.Writer) error) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*"+PARTIAL_SUFFIX)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// Keeps up to `count` previous versions of the file as `<path>.bak.1` (newest) ... `<path>.bak.<count>`.
+// Current file is copied, not moved, so it stays in place until replaced.
+func RotateBackups(path string, count int) error {
+	if count <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	backup := func(n int) string {
+		return fmt.Sprintf("%s%s.%d", path, BACKUP_SUFFIX, n)
+	}
+	os.Remove(backup(count))
+	for n := count - 1; n >= 1; n-- {
+		if err := os.Rename(backup(n), backup(n+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return WriteFileAtomic(backup(1), func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return err
+	})
+}
+
+// Removes temporary files of interrupted atomic writes in given directories and their subdirectories.
+// Files modified less than `olderThan` ago are kept as they may belong to a running conversion.
+// Returns paths of removed files.
+func SweepTempFiles(dirs []string, olderThan time.Duration) ([]string, error) {
+	removed := make([]string, 0)
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) && path == dir {
+				return filepath.SkipDir
+			}
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(d.Name(), PARTIAL_SUFFIX) {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil || time.Since(info.ModTime()) < olderThan {
+				return nil
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			removed = append(removed, path)
+			return nil
+		})
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// Removes `aarListConfig.tmp` left in `dir` by released versions, which wrote AAR list config
+// through this file in the working directory. Returns path of removed file or empty string.
+func SweepLegacyConfigTempFile(dir string, olderThan time.Duration) (string, error) {
+	path := filepath.Join(dir, AAR_CONFIG_TMP_FILENAME)
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || time.Since(info.ModTime()) < olderThan {
+		return "", nil
+	}
+	if err := os.Remove(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Flushes directory entry after rename. Not supported on Windows, errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
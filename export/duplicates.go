@@ -0,0 +1,151 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// What to do with AAR that is already listed in AAR list config with the same guid.
+type Duplicates int
+
+const (
+	DuplicatesReplace Duplicates = iota // overwrite archive of the listed AAR
+	DuplicatesKeep                      // export one more archive with numbered name
+	DuplicatesSkip                      // do not export the AAR again
+)
+
+// Parses duplicates mode used in CLI: `replace`, `keep` or `skip`.
+func ParseDuplicates(name string) (Duplicates, bool) {
+	switch strings.ToLower(name) {
+	case "replace", "":
+		return DuplicatesReplace, true
+	case "keep":
+		return DuplicatesKeep, true
+	case "skip":
+		return DuplicatesSkip, true
+	}
+	return DuplicatesReplace, false
+}
+
+// Archives known by AAR list config and present on disk.
+// Used to pick archive names that don't overwrite other AARs.
+type archiveIndex struct {
+	dir    string
+	naming *Naming
+	byGuid map[string]*AARConfigEntry
+	legacy map[string][]*AARConfigEntry // entries without guid by date, title and terrain, in config order
+	links  map[string]bool
+	claim  map[string]string // links returned by `find` in this run, with guid of the AAR that got it
+}
+
+// Location of AAR archive.
+type archivePlace struct {
+	Dir  string // directory relative to AAR directory, `/`-separated
+	Name string // archive name without extension
+	Link string
+}
+
+func (p *archivePlace) Path(root string) string {
+	return filepath.Join(root, filepath.FromSlash(p.Dir), p.Name+".zip")
+}
+
+func newArchiveIndex(dir string, naming *Naming, entries []*AARConfigEntry) *archiveIndex {
+	idx := &archiveIndex{
+		dir:    dir,
+		naming: naming,
+		byGuid: make(map[string]*AARConfigEntry),
+		legacy: make(map[string][]*AARConfigEntry),
+		links:  make(map[string]bool),
+		claim:  make(map[string]string),
+	}
+	for _, e := range entries {
+		idx.add(e)
+	}
+	return idx
+}
+
+func (idx *archiveIndex) add(e *AARConfigEntry) {
+	idx.links[e.Link] = true
+	if e.Guid == "" {
+		key := legacyKey(e.Date, e.Title, e.Terrain)
+		idx.legacy[key] = append(idx.legacy[key], e)
+		return
+	}
+	if _, ok := idx.byGuid[e.Guid]; !ok {
+		idx.byGuid[e.Guid] = e
+	}
+}
+
+// Returns listed entry of AAR with given guid, if any.
+// Entries written before guid was stored are matched by date, title and terrain instead,
+// each of them is returned once: the oldest one first, as AARs are written in RPT order and listed newest first.
+// Link returned for one AAR is not returned for another one, as older versions listed several AARs
+// of the same name with the same link.
+func (idx *archiveIndex) find(guid, date, title, terrain string) *AARConfigEntry {
+	if e, ok := idx.byGuid[guid]; guid != "" && ok {
+		if claimed, ok := idx.claim[e.Link]; ok && claimed != guid {
+			return nil
+		}
+		idx.claim[e.Link] = guid
+		return e
+	}
+
+	key := legacyKey(date, title, terrain)
+	for entries := idx.legacy[key]; len(entries) > 0; entries = idx.legacy[key] {
+		e := entries[len(entries)-1]
+		idx.legacy[key] = entries[:len(entries)-1]
+		if _, ok := idx.claim[e.Link]; ok {
+			continue
+		}
+		idx.claim[e.Link] = guid
+		return e
+	}
+	return nil
+}
+
+func legacyKey(date, title, terrain string) string {
+	return strings.Join([]string{date, title, terrain}, "\x00")
+}
+
+// Picks archive location for AAR with given name fields: `<name>`, then `<name>.2`, `<name>.3` and so on.
+// Location linked as `replaceLink` is returned as is, others are skipped while used by listed entries or files on disk.
+func (idx *archiveIndex) place(f *NameFields, replaceLink string) (*archivePlace, error) {
+	dir, err := idx.naming.AARDir(f)
+	if err != nil {
+		return nil, err
+	}
+	name, err := idx.naming.AARFilename(f)
+	if err != nil {
+		return nil, err
+	}
+
+	for n := 1; ; n++ {
+		p := &archivePlace{Dir: dir, Name: name}
+		if n > 1 {
+			p.Name = fmt.Sprintf("%s.%d", name, n)
+		}
+
+		linkFields := *f
+		linkFields.Dir, linkFields.Archive = p.Dir, p.Name+".zip"
+		if p.Link, err = idx.naming.AARLink(&linkFields); err != nil {
+			return nil, err
+		}
+
+		if replaceLink != "" && p.Link == replaceLink {
+			return p, nil
+		}
+		if idx.links[p.Link] {
+			continue
+		}
+		_, err := os.Stat(p.Path(idx.dir))
+		if errors.Is(err, os.ErrNotExist) {
+			return p, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package export
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/10Dozen/ts_aar_parser/aar"
+)
+
+const testDate = "2024-11-22"
+
+func testAAR(guid, name string) *aar.Converted {
+	return &aar.Converted{
+		Guid: guid,
+		Metadata: &aar.Metadata{
+			Terrain: "Altis",
+			Name:    name,
+			Players: make([]*aar.Player, 0),
+			Objects: &aar.Objects{Units: make([]*aar.MetadataUnit, 0), Vehicles: make([]*aar.MetadataVehicle, 0)},
+		},
+	}
+}
+
+func TestAARWriterDuplicates(t *testing.T) {
+	const (
+		co10   = "aars/AAR.2024-11-22.Altis.CO10_Test.zip"
+		co10_2 = "aars/AAR.2024-11-22.Altis.CO10_Test.2.zip"
+		tvt    = "aars/AAR.2024-11-22.Altis.TVT.zip"
+	)
+	listed := func(link, guid string) *AARConfigEntry {
+		title := "CO10 Test"
+		if link == tvt {
+			title = "TVT"
+		}
+		return NewAARConfigEntry(testDate, title, "Altis", link, guid)
+	}
+	renamed := NewAARConfigEntry("2024-11-21", "CO10 Old", "Altis", co10, "g1")
+
+	tests := []struct {
+		name       string
+		duplicates Duplicates
+		existing   []*AARConfigEntry
+		aars       []*aar.Converted
+		want       []string // `link guid` of config entries
+		skipped    int
+		unchanged  bool // nothing is written, config is not rewritten
+	}{
+		{
+			name:       "new AAR",
+			duplicates: DuplicatesReplace,
+			existing:   []*AARConfigEntry{listed(tvt, "g9")},
+			aars:       []*aar.Converted{testAAR("g1", "CO10 Test")},
+			want:       []string{co10 + " g1", tvt + " g9"},
+		},
+		{
+			name:       "guid replace",
+			duplicates: DuplicatesReplace,
+			existing:   []*AARConfigEntry{listed(tvt, "g9"), listed(co10, "g1")},
+			aars:       []*aar.Converted{testAAR("g1", "CO10 Test")},
+			want:       []string{tvt + " g9", co10 + " g1"},
+		},
+		{
+			name:       "guid replace of renamed AAR",
+			duplicates: DuplicatesReplace,
+			existing:   []*AARConfigEntry{listed(tvt, "g9"), renamed},
+			aars:       []*aar.Converted{testAAR("g1", "CO10 Test")},
+			want:       []string{tvt + " g9", co10 + " g1"},
+		},
+		{
+			name:       "guid skip",
+			duplicates: DuplicatesSkip,
+			existing:   []*AARConfigEntry{listed(co10, "g1")},
+			aars:       []*aar.Converted{testAAR("g1", "CO10 Test")},
+			want:       []string{co10 + " g1"},
+			skipped:    1,
+			unchanged:  true,
+		},
+		{
+			name:       "guid keep adds suffix",
+			duplicates: DuplicatesKeep,
+			existing:   []*AARConfigEntry{listed(co10, "g1")},
+			aars:       []*aar.Converted{testAAR("g1", "CO10 Test")},
+			want:       []string{co10_2 + " g1", co10 + " g1"},
+		},
+		{
+			name:       "same name, other guid",
+			duplicates: DuplicatesReplace,
+			existing:   []*AARConfigEntry{listed(co10, "g1")},
+			aars:       []*aar.Converted{testAAR("g2", "CO10 Test")},
+			want:       []string{co10_2 + " g2", co10 + " g1"},
+		},
+		{
+			name:       "legacy replace",
+			duplicates: DuplicatesReplace,
+			existing:   []*AARConfigEntry{listed(tvt, ""), listed(co10, "")},
+			aars:       []*aar.Converted{testAAR("g1", "CO10 Test")},
+			want:       []string{tvt + " ", co10 + " g1"},
+		},
+		{
+			name:       "legacy skip fills guid",
+			duplicates: DuplicatesSkip,
+			existing:   []*AARConfigEntry{listed(co10, "")},
+			aars:       []*aar.Converted{testAAR("g1", "CO10 Test")},
+			want:       []string{co10 + " g1"},
+			skipped:    1,
+		},
+		{
+			name:       "legacy keep adds suffix",
+			duplicates: DuplicatesKeep,
+			existing:   []*AARConfigEntry{listed(co10, "")},
+			aars:       []*aar.Converted{testAAR("g1", "CO10 Test")},
+			want:       []string{co10_2 + " g1", co10 + " "},
+		},
+		{
+			name:       "legacy entries matched once each",
+			duplicates: DuplicatesReplace,
+			existing:   []*AARConfigEntry{listed(co10_2, ""), listed(co10, "")},
+			aars:       []*aar.Converted{testAAR("g1", "CO10 Test"), testAAR("g2", "CO10 Test")},
+			want:       []string{co10_2 + " g2", co10 + " g1"},
+		},
+		{
+			name:       "shared legacy link replaced once",
+			duplicates: DuplicatesReplace,
+			existing:   []*AARConfigEntry{listed(co10, ""), listed(co10, "")},
+			aars:       []*aar.Converted{testAAR("g1", "CO10 Test"), testAAR("g2", "CO10 Test")},
+			want:       []string{co10_2 + " g2", co10 + " g1"},
+		},
+		{
+			name:       "shared legacy link skipped once",
+			duplicates: DuplicatesSkip,
+			existing:   []*AARConfigEntry{listed(co10, ""), listed(co10, "")},
+			aars:       []*aar.Converted{testAAR("g1", "CO10 Test"), testAAR("g2", "CO10 Test")},
+			want:       []string{co10_2 + " g2", co10 + " g1"},
+			skipped:    1,
+		},
+		{
+			name:       "guid link claimed by legacy match",
+			duplicates: DuplicatesReplace,
+			existing:   []*AARConfigEntry{listed(co10, "g2"), listed(co10, "")},
+			aars:       []*aar.Converted{testAAR("g1", "CO10 Test"), testAAR("g2", "CO10 Test")},
+			want:       []string{co10_2 + " g2", co10 + " g1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, e := range tt.existing {
+				path := filepath.Join(dir, filepath.FromSlash(e.Link))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cfgPath := filepath.Join(dir, AAR_CONFIG_FILENAME)
+			content, err := FormatAARListConfig(tt.existing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(cfgPath, content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			naming, err := NewNaming(Templates{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, err := NewAARWriter(dir, testDate, tt.duplicates, naming)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, a := range tt.aars {
+				if err := w.Write(context.Background(), a); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := ReadAARListConfig(cfgPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(entries))
+			for _, e := range entries {
+				got = append(got, e.Link+" "+e.Guid)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("config entries %q, want %q", got, tt.want)
+			}
+			if len(w.Skipped) != tt.skipped {
+				t.Errorf("skipped %d, want %d", len(w.Skipped), tt.skipped)
+			}
+			// -- Config is rewritten with a backup only when entries were added or updated
+			_, err = os.Stat(cfgPath + BACKUP_SUFFIX + ".1")
+			if rewritten := !tt.unchanged; rewritten == os.IsNotExist(err) {
+				t.Errorf("config rewritten %v, backup: %v", rewritten, err)
+			}
+
+			// -- Each written AAR has it's own archive, listed archives are empty files
+			archives, err := filepath.Glob(filepath.Join(dir, "aars", "*.zip"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			written := 0
+			for _, path := range archives {
+				if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+					written++
+				}
+			}
+			if want := len(tt.aars) - tt.skipped; written != want {
+				t.Errorf("%d archives written, want %d", written, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"github.com/10Dozen/ts_aar_parser/aar"
+	"github.com/10Dozen/ts_aar_parser/orbat"
+)
+
+const (
+	AAR_CONFIG_FILENAME string = "aarListConfig.ini"
+	AAR_DATA_PREFIX            = "aarFileData = "
+)
+
+var windowsFsRestrictedRE *regexp.Regexp = regexp.MustCompile(`[\s:*?<>|\\/"]`)
+
+// Writes ORBATs as JSON file named by `naming` (`ORBAT.<date>.json` by default) into given directory.
+// Returns path to the created file.
+func WriteORBAT(ctx context.Context, dir, date string, orbats []*orbat.ORBAT, naming *Naming) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	filename, err := naming.ORBATFilename(NewNameFields(date))
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, filename)
+	content, err := json.MarshalIndent(orbats, "", "    ")
+	if err != nil {
+		return "", fmt.Errorf("failed to convert ORBAT to JSON: %w", err)
+	}
+
+	err = WriteFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to export ORBAT to %s: %w", path, err)
+	}
+
+	return path, nil
+}
+
+// Writes each converted AAR as zip archive into AAR directory `dir` and prepends them to `<dir>/aarListConfig.ini`.
+// See `AARWriter` for archive naming and duplicates handling.
+// Returns listed entries of AARs skipped as duplicates.
+// When `ctx` is cancelled, no more archives are written and AAR list config is left untouched.
+func WriteAARs(ctx context.Context, dir, reportDate string, aars []*aar.Converted, duplicates Duplicates, naming *Naming) ([]*AARConfigEntry, error) {
+	writer, err := NewAARWriter(dir, reportDate, duplicates, naming)
+	if err != nil {
+		return nil, err
+	}
+	for _, converted := range aars {
+		if err := writer.Write(ctx, converted); err != nil {
+			return nil, err
+		}
+	}
+	return writer.Skipped, writer.Close()
+}
+
+// Writes converted AARs of a single report date one by one, so each AAR may be released right after it's written.
+// Archive location and link are defined by `naming`, by default it's `aars/AAR.<date>.<terrain>.<name>.zip`.
+// Archive never overwrites archive of another AAR: on name collision numbered suffix is added, e.g. `AAR.<date>.<terrain>.<name>.2.zip`.
+// AAR already listed in the config with the same guid is handled according to `duplicates`.
+// Player statistics and friendly fire incidents are written next to the archive, see `WritePlayerStats` and `WriteFriendlyFire`.
+// Written AARs are prepended to `<dir>/aarListConfig.ini` on `Close`.
+type AARWriter struct {
+	Skipped      []*AARConfigEntry     // listed entries of AARs skipped as duplicates
+	FriendlyFire []*FriendlyFireReport // friendly fire incidents of written AARs that have any
+
+	dir        string
+	reportDate string
+	duplicates Duplicates
+	cfgPath    string
+	existing   []*AARConfigEntry
+	index      *archiveIndex
+	entries    []*AARConfigEntry
+	replaced   map[*AARConfigEntry]*AARConfigEntry // listed entries replaced by written ones
+}
+
+func NewAARWriter(dir, reportDate string, duplicates Duplicates, naming *Naming) (*AARWriter, error) {
+	cfgPath := filepath.Join(dir, AAR_CONFIG_FILENAME)
+	existing, err := ReadAARListConfig(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+	return &AARWriter{
+		Skipped:      make([]*AARConfigEntry, 0),
+		FriendlyFire: make([]*FriendlyFireReport, 0),
+		dir:          dir,
+		reportDate:   reportDate,
+		duplicates:   duplicates,
+		cfgPath:      cfgPath,
+		existing:     existing,
+		index:        newArchiveIndex(dir, naming, existing),
+		entries:      make([]*AARConfigEntry, 0),
+		replaced:     make(map[*AARConfigEntry]*AARConfigEntry),
+	}, nil
+}
+
+// Writes AAR archive. AAR skipped as duplicate is added to `Skipped`.
+func (w *AARWriter) Write(ctx context.Context, converted *aar.Converted) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	replaceLink := ""
+	prev := w.index.find(converted.Guid, w.reportDate, converted.Metadata.Name, converted.Metadata.Terrain)
+	switch {
+	case prev != nil && w.duplicates == DuplicatesSkip:
+		w.Skipped = append(w.Skipped, prev)
+		if prev.Guid == "" {
+			// -- Entry of an older version gets guid of the AAR
+			entry := NewAARConfigEntry(prev.Date, prev.Title, prev.Terrain, prev.Link, converted.Guid)
+			w.replaced[prev] = entry
+			w.entries = append(w.entries, entry)
+		}
+		return nil
+	case prev != nil && w.duplicates == DuplicatesReplace:
+		replaceLink = prev.Link
+	}
+
+	place, err := w.index.place(NewAARNameFields(w.reportDate, converted), replaceLink)
+	if err != nil {
+		return fmt.Errorf("failed to name AAR %s: %w", converted.Metadata.Name, err)
+	}
+
+	path := place.Path(w.dir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	err = writeAARArchive(
+		path,
+		fmt.Sprintf("%s.%s", place.Name, "json"),
+		converted,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to export AAR %s: %w", converted.Metadata.Name, err)
+	}
+	// -- Timeline is scanned for kills once for both reports
+	kills := converted.Kills()
+	if err := WritePlayerStats(path, converted.PlayerStats(kills)); err != nil {
+		return fmt.Errorf("failed to export AAR %s player statistics: %w", converted.Metadata.Name, err)
+	}
+	incidents := converted.FriendlyFire(kills)
+	if err := WriteFriendlyFire(path, incidents); err != nil {
+		return fmt.Errorf("failed to export AAR %s friendly fire report: %w", converted.Metadata.Name, err)
+	}
+
+	// -- Update config
+	entry := NewAARConfigEntry(
+		w.reportDate,
+		converted.Metadata.Name,
+		converted.Metadata.Terrain,
+		place.Link,
+		converted.Guid,
+	)
+	w.index.add(entry)
+	w.entries = append(w.entries, entry)
+	if prev != nil && prev.Link == entry.Link {
+		w.replaced[prev] = entry
+	}
+	if len(incidents) > 0 {
+		w.FriendlyFire = append(w.FriendlyFire, &FriendlyFireReport{
+			Title:     entry.Title,
+			Link:      entry.Link,
+			Incidents: incidents,
+		})
+	}
+	return nil
+}
+
+// Prepends written AARs to AAR list config, entries of replaced archives are updated in place.
+// Config and it's backups are left untouched when nothing was written.
+func (w *AARWriter) Close() error {
+	if len(w.entries) == 0 {
+		return nil
+	}
+	existing := make([]*AARConfigEntry, len(w.existing))
+	for i, e := range w.existing {
+		if upd, ok := w.replaced[e]; ok {
+			e = upd
+		}
+		existing[i] = e
+	}
+	entries := slices.Clone(w.entries)
+	slices.Reverse(entries)
+	return WriteAARListConfig(w.cfgPath, MergeAARListConfig(existing, entries))
+}
+
+// Writes AAR as JSON file `filename` inside of zip archive at `path`.
+// Archive is written atomically, existing archive is replaced only when the new one is complete.
+func writeAARArchive(path, filename string, converted *aar.Converted) error {
+	// -- Create ZIP archive
+	return WriteFileAtomic(path, func(w io.Writer) error {
+		writer := zip.NewWriter(w)
+		archived, err := writer.Create(filename)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(archived, AAR_DATA_PREFIX); err != nil {
+			return err
+		}
+		if err := converted.EncodeJSON(archived); err != nil {
+			return err
+		}
+
+		return writer.Close()
+	})
+}
@@ -0,0 +1,202 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/10Dozen/ts_aar_parser/aar"
+)
+
+const (
+	DEFAULT_AAR_DIR_TEMPLATE        string = "aars"
+	DEFAULT_AAR_FILENAME_TEMPLATE          = "AAR.{{.Date}}.{{.Terrain}}.{{safe .Name}}"
+	DEFAULT_AAR_LINK_TEMPLATE              = "{{.Dir}}/{{.Archive}}"
+	DEFAULT_ORBAT_FILENAME_TEMPLATE        = "ORBAT.{{.Date}}.json"
+)
+
+// Output name templates, `Templates` section of config.json. Empty templates are replaced by defaults.
+// Templates use `text/template` syntax with `NameFields` as data and `slug`, `safe`, `lower` functions.
+type Templates struct {
+	AARDir        string // directory of AAR archives, relative to AAR directory, e.g. `aars/{{.Year}}/{{.Month}}`
+	AARFilename   string // AAR archive name without `.zip`, also used for JSON file inside of the archive
+	AARLink       string // link to AAR archive written to AAR list config
+	ORBATFilename string // ORBAT file name, relative to ORBAT directory
+}
+
+// Data of output name templates.
+type NameFields struct {
+	Date             string // report date, YYYY-MM-DD
+	Year, Month, Day string // parts of the report date
+	Terrain          string
+	Name             string
+	Guid             string
+	TimeLabel        string // RPT time label of the AAR header line, e.g. `21:05:33`
+
+	// -- Link template only
+	Dir     string // rendered AAR directory, `/`-separated
+	Archive string // AAR archive file name with `.zip`
+}
+
+// Compiled output name templates.
+type Naming struct {
+	aarDirSource  string
+	aarDir        *template.Template
+	aarFilename   *template.Template
+	aarLink       *template.Template
+	orbatFilename *template.Template
+}
+
+var templateFuncs = template.FuncMap{
+	"slug":  Slug,
+	"safe":  SafeFilename,
+	"lower": strings.ToLower,
+}
+
+// Compiles given templates, empty ones are replaced by defaults.
+func NewNaming(t Templates) (*Naming, error) {
+	n := &Naming{aarDirSource: withDefault(t.AARDir, DEFAULT_AAR_DIR_TEMPLATE)}
+
+	var err error
+	compile := func(name, text, def string) *template.Template {
+		if err != nil {
+			return nil
+		}
+		var tmpl *template.Template
+		tmpl, err = template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(withDefault(text, def))
+		if err != nil {
+			err = fmt.Errorf("invalid %s template: %w", name, err)
+		}
+		return tmpl
+	}
+	n.aarDir = compile("AARDir", t.AARDir, DEFAULT_AAR_DIR_TEMPLATE)
+	n.aarFilename = compile("AARFilename", t.AARFilename, DEFAULT_AAR_FILENAME_TEMPLATE)
+	n.aarLink = compile("AARLink", t.AARLink, DEFAULT_AAR_LINK_TEMPLATE)
+	n.orbatFilename = compile("ORBATFilename", t.ORBATFilename, DEFAULT_ORBAT_FILENAME_TEMPLATE)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Naming with default templates, matches layout of the web player.
+func DefaultNaming() *Naming {
+	n, err := NewNaming(Templates{})
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Returns fields of the report date, without AAR data.
+func NewNameFields(date string) *NameFields {
+	f := &NameFields{Date: date}
+	if parts := strings.SplitN(date, "-", 3); len(parts) == 3 {
+		f.Year, f.Month, f.Day = parts[0], parts[1], parts[2]
+	}
+	return f
+}
+
+// Returns fields of the converted AAR found in RPT of given report date.
+func NewAARNameFields(date string, converted *aar.Converted) *NameFields {
+	f := NewNameFields(date)
+	f.Terrain = converted.Metadata.Terrain
+	f.Name = converted.Metadata.Name
+	f.Guid = converted.Guid
+	f.TimeLabel = converted.TimeLabel
+	return f
+}
+
+// Returns directory of AAR archives, relative to AAR directory and `/`-separated.
+func (n *Naming) AARDir(f *NameFields) (string, error) {
+	dir, err := render(n.aarDir, f)
+	if err != nil {
+		return "", err
+	}
+	dir = path.Clean(filepath.ToSlash(dir))
+	if !filepath.IsLocal(filepath.FromSlash(dir)) {
+		return "", fmt.Errorf("AAR directory %q must be relative and stay inside of AAR directory", dir)
+	}
+	return dir, nil
+}
+
+// Returns static part of AAR directory template, that contains all AAR archives.
+func (n *Naming) AARRoot() string {
+	static, _, dynamic := strings.Cut(n.aarDirSource, "{{")
+	if dynamic {
+		static = static[:max(strings.LastIndex(static, "/"), 0)]
+	}
+	return filepath.FromSlash(path.Clean("./" + static))
+}
+
+// Returns AAR archive name without extension.
+func (n *Naming) AARFilename(f *NameFields) (string, error) {
+	name, err := render(n.aarFilename, f)
+	if err != nil {
+		return "", err
+	}
+	return name, checkFilename("AAR", name)
+}
+
+// Returns link to AAR archive. `Dir` and `Archive` fields must be set.
+func (n *Naming) AARLink(f *NameFields) (string, error) {
+	return render(n.aarLink, f)
+}
+
+// Returns ORBAT file name.
+func (n *Naming) ORBATFilename(f *NameFields) (string, error) {
+	name, err := render(n.orbatFilename, f)
+	if err != nil {
+		return "", err
+	}
+	return name, checkFilename("ORBAT", name)
+}
+
+// Makes lowercase URL-friendly name: letters and digits separated by single `-`.
+func Slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+// Replaces whitespaces and characters not allowed in Windows file names with `_`.
+func SafeFilename(s string) string {
+	return windowsFsRestrictedRE.ReplaceAllString(s, `_`)
+}
+
+func render(tmpl *template.Template, f *NameFields) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, f); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func checkFilename(kind, name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("invalid %s file name %q, use directory template for subdirectories", kind, name)
+	}
+	return nil
+}
+
+func withDefault(value, def string) string {
+	if strings.TrimSpace(value) == "" {
+		return def
+	}
+	return value
+}
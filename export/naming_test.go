@@ -0,0 +1,172 @@
+package export
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testNameFields() *NameFields {
+	f := NewAARNameFields("2024-11-22", testAAR("g1", `CO10 "Dawn": Part 1/2`))
+	f.TimeLabel = "21:05:33"
+	return f
+}
+
+func TestNamingPlaceholders(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates Templates
+		dir       string
+		filename  string
+		link      string
+		orbat     string
+	}{
+		{
+			name:     "defaults",
+			dir:      "aars",
+			filename: "AAR.2024-11-22.Altis.CO10__Dawn___Part_1_2",
+			link:     "aars/AAR.2024-11-22.Altis.CO10__Dawn___Part_1_2.zip",
+			orbat:    "ORBAT.2024-11-22.json",
+		},
+		{
+			name: "date parts, guid and functions",
+			templates: Templates{
+				AARDir:        "aars/{{.Year}}/{{.Month}}",
+				AARFilename:   "{{.Day}}-{{lower .Terrain}}-{{slug .Name}}-{{.Guid}}",
+				AARLink:       "/replays/{{.Dir}}/{{.Archive}}",
+				ORBATFilename: "orbat-{{.Year}}{{.Month}}{{.Day}}.json",
+			},
+			dir:      "aars/2024/11",
+			filename: "22-altis-co10-dawn-part-1-2-g1",
+			link:     "/replays/aars/2024/11/22-altis-co10-dawn-part-1-2-g1.zip",
+			orbat:    "orbat-20241122.json",
+		},
+		{
+			name:      "time label",
+			templates: Templates{AARDir: "./aars/{{.Date}}/", AARFilename: `{{safe .TimeLabel}}`},
+			dir:       "aars/2024-11-22",
+			filename:  "21_05_33",
+			link:      "aars/2024-11-22/21_05_33.zip",
+			orbat:     "ORBAT.2024-11-22.json",
+		},
+		{
+			name:      "blank templates use defaults",
+			templates: Templates{AARDir: " ", AARFilename: "\t", AARLink: "", ORBATFilename: "\n"},
+			dir:       "aars",
+			filename:  "AAR.2024-11-22.Altis.CO10__Dawn___Part_1_2",
+			link:      "aars/AAR.2024-11-22.Altis.CO10__Dawn___Part_1_2.zip",
+			orbat:     "ORBAT.2024-11-22.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := NewNaming(tt.templates)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := testNameFields()
+
+			dir, err := n.AARDir(f)
+			if err != nil || dir != tt.dir {
+				t.Errorf("AARDir() = %q, %v, want %q", dir, err, tt.dir)
+			}
+			filename, err := n.AARFilename(f)
+			if err != nil || filename != tt.filename {
+				t.Errorf("AARFilename() = %q, %v, want %q", filename, err, tt.filename)
+			}
+			f.Dir, f.Archive = dir, filename+".zip"
+			link, err := n.AARLink(f)
+			if err != nil || link != tt.link {
+				t.Errorf("AARLink() = %q, %v, want %q", link, err, tt.link)
+			}
+			orbat, err := n.ORBATFilename(NewNameFields(f.Date))
+			if err != nil || orbat != tt.orbat {
+				t.Errorf("ORBATFilename() = %q, %v, want %q", orbat, err, tt.orbat)
+			}
+		})
+	}
+}
+
+func TestNamingInvalidTemplates(t *testing.T) {
+	for name, tmpl := range map[string]Templates{
+		"syntax":        {AARFilename: "AAR.{{.Date"},
+		"unknown func":  {AARDir: "{{upper .Terrain}}"},
+		"link syntax":   {AARLink: "{{end}}"},
+		"orbat syntax":  {ORBATFilename: "{{if}}.json"},
+		"missing close": {AARDir: "aars/{{.Year}"},
+	} {
+		if _, err := NewNaming(tmpl); err == nil {
+			t.Errorf("%s: expected error for %+v", name, tmpl)
+		}
+	}
+
+	// -- Templates that compile, but can't be rendered into a valid name
+	tests := []struct {
+		name   string
+		tmpl   Templates
+		render func(n *Naming, f *NameFields) (string, error)
+	}{
+		{"unknown field", Templates{AARFilename: "{{.Mission}}"}, (*Naming).AARFilename},
+		{"filename with slash", Templates{AARFilename: "{{.Year}}/{{.Name}}"}, (*Naming).AARFilename},
+		{"empty filename", Templates{AARFilename: "{{.TimeLabel}}"}, (*Naming).AARFilename},
+		{"dot filename", Templates{AARFilename: ".{{.TimeLabel}}"}, (*Naming).AARFilename},
+		{"orbat with backslash", Templates{ORBATFilename: `orbat\{{.Date}}.json`}, (*Naming).ORBATFilename},
+		{"dir outside", Templates{AARDir: "../{{.Year}}"}, (*Naming).AARDir},
+		{"dir absolute", Templates{AARDir: "/var/www/{{.Year}}"}, (*Naming).AARDir},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := NewNaming(tt.tmpl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := NewNameFields("2024-11-22")
+			if name, err := tt.render(n, f); err == nil {
+				t.Errorf("expected error, got %q", name)
+			}
+		})
+	}
+}
+
+func TestNamingAARRoot(t *testing.T) {
+	tests := map[string]string{
+		"":                          "aars",
+		"replays":                   "replays",
+		"aars/{{.Year}}/{{.Month}}": "aars",
+		"aars/y{{.Year}}":           "aars",
+		"{{.Year}}":                 ".",
+		"./replays/aars/":           filepath.Join("replays", "aars"),
+	}
+	for dir, want := range tests {
+		n, err := NewNaming(Templates{AARDir: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := n.AARRoot(); got != want {
+			t.Errorf("AARRoot() of %q = %q, want %q", dir, got, want)
+		}
+	}
+}
+
+func TestSlugAndSafeFilename(t *testing.T) {
+	tests := []struct {
+		in, slug, safe string
+	}{
+		{"CO10 Test", "co10-test", "CO10_Test"},
+		{`  Op "Dawn": 1/2 `, "op-dawn-1-2", `__Op__Dawn___1_2_`},
+		{"Операция Буря", "операция-буря", "Операция_Буря"},
+		{`a\b|c?d*e<f>g`, "a-b-c-d-e-f-g", "a_b_c_d_e_f_g"},
+		{"---", "", "---"},
+	}
+	for _, tt := range tests {
+		if got := Slug(tt.in); got != tt.slug {
+			t.Errorf("Slug(%q) = %q, want %q", tt.in, got, tt.slug)
+		}
+		if got := SafeFilename(tt.in); got != tt.safe {
+			t.Errorf("SafeFilename(%q) = %q, want %q", tt.in, got, tt.safe)
+		}
+		if strings.ContainsAny(SafeFilename(tt.in), `/\:*?"<>| `) {
+			t.Errorf("SafeFilename(%q) has restricted characters", tt.in)
+		}
+	}
+}
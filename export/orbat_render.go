@@ -0,0 +1,268 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/10Dozen/ts_aar_parser/orbat"
+)
+
+const (
+	DISCORD_MESSAGE_LIMIT int = 2000 // max characters of a single Discord message
+)
+
+// Text format of ORBAT written next to the JSON file.
+type ORBATFormat int
+
+const (
+	ORBATMarkdown ORBATFormat = iota // Markdown tables, `<orbat>.md`
+	ORBATBBCode                      // phpBB BBCode, `<orbat>.bbcode.txt`
+	ORBATDiscord                     // Discord messages of `DISCORD_MESSAGE_LIMIT` characters, `<orbat>.discord.txt`
+)
+
+// Parses ORBAT format name used in CLI and config: `markdown` (`md`), `bbcode` or `discord`.
+func ParseORBATFormat(name string) (ORBATFormat, bool) {
+	switch strings.ToLower(name) {
+	case "markdown", "md":
+		return ORBATMarkdown, true
+	case "bbcode":
+		return ORBATBBCode, true
+	case "discord":
+		return ORBATDiscord, true
+	}
+	return ORBATMarkdown, false
+}
+
+// File name suffix replacing extension of the JSON file.
+func (f ORBATFormat) Suffix() string {
+	switch f {
+	case ORBATBBCode:
+		return ".bbcode.txt"
+	case ORBATDiscord:
+		return ".discord.txt"
+	}
+	return ".md"
+}
+
+// Writes ORBATs in given format next to the ORBAT JSON file at `jsonPath`, e.g. `ORBAT.<date>.md`.
+// Returns path to the created file.
+func WriteORBATText(ctx context.Context, jsonPath string, format ORBATFormat, orbats []*orbat.ORBAT) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	path := strings.TrimSuffix(jsonPath, filepath.Ext(jsonPath)) + format.Suffix()
+	var content string
+	switch format {
+	case ORBATBBCode:
+		content = RenderBBCode(orbats)
+	case ORBATDiscord:
+		// -- Messages are separated by numbered lines, which are not part of messages
+		messages := RenderDiscord(orbats, DISCORD_MESSAGE_LIMIT)
+		var sb strings.Builder
+		for i, m := range messages {
+			fmt.Fprintf(&sb, "===== %d/%d =====\n%s\n\n", i+1, len(messages), m)
+		}
+		content = sb.String()
+	default:
+		content = RenderMarkdown(orbats)
+	}
+
+	err := WriteFileAtomic(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, content)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to export ORBAT to %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// Leaders of the slot, HQ, SL and TL sections of the rendered ORBAT.
+type leaderSection struct {
+	slot    string
+	leaders []*orbat.Leader
+}
+
+func leaderSections(o *orbat.ORBAT) []leaderSection {
+	if o.Leaders == nil {
+		return nil
+	}
+	return []leaderSection{
+		{orbat.SlotHQ, o.Leaders.HQ},
+		{orbat.SlotSL, o.Leaders.SquadLeaders},
+		{orbat.SlotTL, o.Leaders.TeamLeaders},
+	}
+}
+
+// Escapes characters with special meaning in Markdown, also used by Discord.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `~`, `\~`, `|`, `\|`,
+	`[`, `\[`, `]`, `\]`, `<`, `\<`, `>`, `\>`, `#`, `\#`,
+)
+
+// Renders ORBATs as Markdown: leaders tables by slot and a table of units for each group.
+func RenderMarkdown(orbats []*orbat.ORBAT) string {
+	md := markdownEscaper.Replace
+	var sb strings.Builder
+	for i, o := range orbats {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "## %s\n", md(o.Mission))
+
+		for _, section := range leaderSections(o) {
+			if len(section.leaders) == 0 {
+				continue
+			}
+			fmt.Fprintf(&sb, "\n### %s\n\n| Side | Group | Role | Name |\n|---|---|---|---|\n", section.slot)
+			for _, l := range section.leaders {
+				fmt.Fprintf(&sb, "| %s | %s | %s | %s |\n", md(l.Side()), md(l.Group), md(l.Role), md(l.Name))
+			}
+		}
+
+		for _, side := range o.SideList() {
+			fmt.Fprintf(&sb, "\n### %s\n", md(side.Name))
+			for _, group := range side.GroupList() {
+				fmt.Fprintf(&sb, "\n#### %s\n\n| Role | Rank | Name |\n|---|---|---|\n", md(group.Name))
+				for _, u := range group.Units {
+					fmt.Fprintf(&sb, "| %s | %s | %s |\n", md(u.Role), md(u.Rank), md(u.Name))
+				}
+			}
+		}
+	}
+	return sb.String()
+}
+
+// Renders ORBATs as phpBB BBCode: leaders lists by slot and a list of units for each group.
+// BBCode has no escaping, names are written as is.
+func RenderBBCode(orbats []*orbat.ORBAT) string {
+	var sb strings.Builder
+	for i, o := range orbats {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "[size=150][b]%s[/b][/size]\n", o.Mission)
+
+		for _, section := range leaderSections(o) {
+			if len(section.leaders) == 0 {
+				continue
+			}
+			fmt.Fprintf(&sb, "\n[b]%s[/b]\n[list]\n", section.slot)
+			for _, l := range section.leaders {
+				fmt.Fprintf(&sb, "[*]%s %s, %s: [b]%s[/b]\n", l.Side(), l.Group, l.Role, l.Name)
+			}
+			sb.WriteString("[/list]\n")
+		}
+
+		for _, side := range o.SideList() {
+			fmt.Fprintf(&sb, "\n[size=120][b]%s[/b][/size]\n", side.Name)
+			for _, group := range side.GroupList() {
+				fmt.Fprintf(&sb, "[u]%s[/u]\n[list]\n", group.Name)
+				for _, u := range group.Units {
+					fmt.Fprintf(&sb, "[*]%s: [b]%s[/b]\n", unitTitle(u), u.Name)
+				}
+				sb.WriteString("[/list]\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// Renders ORBATs as Discord messages of at most `limit` characters.
+// Messages are split between leaders sections and groups, longer sections are split by lines.
+func RenderDiscord(orbats []*orbat.ORBAT, limit int) []string {
+	md := markdownEscaper.Replace
+
+	// -- Blocks are kept in a single message when possible, headings stay with the first block under them
+	blocks := make([]string, 0)
+	for _, o := range orbats {
+		heading := fmt.Sprintf("# %s\n", md(o.Mission))
+		for _, section := range leaderSections(o) {
+			if len(section.leaders) == 0 {
+				continue
+			}
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "%s**%s**\n", heading, section.slot)
+			for _, l := range section.leaders {
+				fmt.Fprintf(&sb, "- %s %s, %s: **%s**\n", md(l.Side()), md(l.Group), md(l.Role), md(l.Name))
+			}
+			blocks = append(blocks, sb.String())
+			heading = ""
+		}
+
+		for _, side := range o.SideList() {
+			heading += fmt.Sprintf("## %s\n", md(side.Name))
+			for _, group := range side.GroupList() {
+				var sb strings.Builder
+				fmt.Fprintf(&sb, "%s__**%s**__\n", heading, md(group.Name))
+				for _, u := range group.Units {
+					fmt.Fprintf(&sb, "- %s: **%s**\n", md(unitTitle(u)), md(u.Name))
+				}
+				blocks = append(blocks, sb.String())
+				heading = ""
+			}
+		}
+		if heading != "" {
+			blocks = append(blocks, heading)
+		}
+	}
+
+	messages := make([]string, 0)
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			messages = append(messages, strings.TrimRight(current.String(), "\n"))
+			current.Reset()
+		}
+	}
+	add := func(text, sep string) {
+		if current.Len() > 0 && utf8.RuneCountInString(current.String()+sep+text) > limit {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteString(sep)
+		}
+		current.WriteString(text)
+	}
+
+	for _, block := range blocks {
+		block = strings.TrimRight(block, "\n")
+		if utf8.RuneCountInString(block) <= limit {
+			add(block, "\n\n")
+			continue
+		}
+		flush()
+		for _, line := range strings.Split(block, "\n") {
+			for _, part := range splitRunes(line, limit) {
+				add(part, "\n")
+			}
+		}
+		flush()
+	}
+	flush()
+	return messages
+}
+
+// Role and rank of the unit, e.g. `SL (SERGEANT)`.
+func unitTitle(u *orbat.Unit) string {
+	if u.Rank == "" {
+		return u.Role
+	}
+	return fmt.Sprintf("%s (%s)", u.Role, u.Rank)
+}
+
+// Splits `s` into parts of at most `limit` characters.
+func splitRunes(s string, limit int) []string {
+	runes := []rune(s)
+	parts := make([]string, 0, len(runes)/limit+1)
+	for len(runes) > limit {
+		parts = append(parts, string(runes[:limit]))
+		runes = runes[limit:]
+	}
+	return append(parts, string(runes))
+}
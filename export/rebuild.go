@@ -0,0 +1,225 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+var archiveDateRE = regexp.MustCompile(`^AAR\.(\d{4}-\d{2}-\d{2})\.`)
+
+// Result of AAR list config rebuild.
+type RebuildReport struct {
+	Entries   []*AARConfigEntry // entries of the rebuilt config, newest first
+	Unindexed []string          // archive links that had no entry in the previous config
+	Missing   []*AARConfigEntry // previous config entries with link to missing archive
+	Broken    map[string]error  // archives that can't be read, by link
+}
+
+// Metadata of AAR archive, the rest of AAR is skipped on reading.
+type archiveMetadata struct {
+	Metadata struct {
+		Terrain string `json:"island"`
+		Name    string `json:"name"`
+		Date    string `json:"date"`
+	} `json:"metadata"`
+}
+
+// Regenerates AAR list config entries from AAR archives found in AAR directory `dir`
+// (under static part of AAR directory template, `<dir>/aars` by default). Links are made by `naming`.
+// Previous config at `<dir>/aarListConfig.ini` is only used for the report and as a source of guid and date
+// for old archives without date in metadata and file name. Config file is not written.
+func RebuildAARListConfig(dir string, naming *Naming) (*RebuildReport, error) {
+	existing, err := ReadAARListConfig(filepath.Join(dir, AAR_CONFIG_FILENAME))
+	if err != nil {
+		return nil, err
+	}
+	existingByLink := make(map[string]*AARConfigEntry, len(existing))
+	for _, e := range existing {
+		existingByLink[e.Link] = e
+	}
+
+	archives, err := findArchives(dir, naming.AARRoot())
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RebuildReport{
+		Entries:   make([]*AARConfigEntry, 0, len(archives)),
+		Unindexed: make([]string, 0),
+		Missing:   make([]*AARConfigEntry, 0),
+		Broken:    make(map[string]error),
+	}
+	found := make(map[string]bool, len(archives))
+	for _, rel := range archives {
+		meta, metaErr := readArchiveMetadata(filepath.Join(dir, rel))
+
+		// -- Guid and time label are not stored in archive, link templates using them can't be restored
+		fields := NewNameFields("")
+		if metaErr == nil {
+			date := meta.Metadata.Date
+			if m := archiveDateRE.FindStringSubmatch(filepath.Base(rel)); date == "" && m != nil {
+				date = m[1]
+			}
+			fields = NewNameFields(date)
+			fields.Terrain = meta.Metadata.Terrain
+			fields.Name = meta.Metadata.Name
+		}
+		fields.Dir, fields.Archive = path.Dir(filepath.ToSlash(rel)), filepath.Base(rel)
+		link, err := naming.AARLink(fields)
+		if err != nil {
+			return nil, err
+		}
+		found[link] = true
+
+		prev, indexed := existingByLink[link]
+		if metaErr != nil {
+			// -- Keep known entry of the archive, it may be readable by the web player
+			report.Broken[link] = metaErr
+			if indexed {
+				report.Entries = append(report.Entries, prev)
+			}
+			continue
+		}
+
+		if !indexed {
+			report.Unindexed = append(report.Unindexed, link)
+		}
+
+		date, guid := fields.Date, ""
+		if indexed {
+			guid = prev.Guid
+			if date == "" {
+				date = prev.Date
+			}
+		}
+
+		report.Entries = append(report.Entries, NewAARConfigEntry(
+			date,
+			fields.Name,
+			fields.Terrain,
+			link,
+			guid,
+		))
+	}
+
+	for _, e := range existing {
+		if !found[e.Link] {
+			found[e.Link] = true
+			report.Missing = append(report.Missing, e)
+		}
+	}
+
+	// -- Newest first, same as conversion prepends new AARs
+	slices.SortStableFunc(report.Entries, func(a, b *AARConfigEntry) int {
+		if c := strings.Compare(b.Date, a.Date); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Title, b.Title)
+	})
+	return report, nil
+}
+
+// Returns paths of zip archives under `root` directory of `dir`, relative to `dir`.
+func findArchives(dir, root string) ([]string, error) {
+	archives := make([]string, 0)
+	err := filepath.WalkDir(filepath.Join(dir, root), func(p string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && p == filepath.Join(dir, root) {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(p), ".zip") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		archives = append(archives, rel)
+		return nil
+	})
+	return archives, err
+}
+
+// Reads metadata of AAR from JSON file inside of the archive.
+func readArchiveMetadata(path string) (*archiveMetadata, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	for _, f := range archive.File {
+		if !strings.HasSuffix(strings.ToLower(f.Name), ".json") {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return decodeArchiveMetadata(r)
+	}
+	return nil, fmt.Errorf("no AAR JSON file in %s", filepath.Base(path))
+}
+
+// Decodes `metadata` of AAR JSON, optionally prefixed by `AAR_DATA_PREFIX`.
+// Reading stops right after metadata, so the timeline written after it is not read.
+func decodeArchiveMetadata(r io.Reader) (*archiveMetadata, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if !unicode.IsSpace(rune(b)) {
+			br.UnreadByte()
+			break
+		}
+	}
+	if prefix, _ := br.Peek(len(AAR_DATA_PREFIX)); string(prefix) == AAR_DATA_PREFIX {
+		br.Discard(len(prefix))
+	}
+
+	dec := json.NewDecoder(br)
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected AAR JSON object, got %v", tok)
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		// -- Other keys are skipped, they are expected after metadata
+		if key != "metadata" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		meta := &archiveMetadata{}
+		if err := dec.Decode(&meta.Metadata); err != nil {
+			return nil, err
+		}
+		return meta, nil
+	}
+	return nil, errors.New("no metadata in AAR JSON")
+}
@@ -0,0 +1,42 @@
+package export
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeArchiveMetadata(t *testing.T) {
+	const meta = `{"island":"Altis","name":"CO10 Test","date":"2024-11-22","players":[["Alpha","blufor"]]}`
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{"written by the tool", AAR_DATA_PREFIX + `{"metadata":` + meta + `,"timeline":[[[],[],[]]]}`, false},
+		{"without prefix", `{"metadata":` + meta + `,"timeline":[]}`, false},
+		{"whitespace", "\n\t " + AAR_DATA_PREFIX + "{ \"metadata\" : " + meta + " }\n", false},
+		{"metadata after other keys", `{"version":2,"extra":{"a":[1]},"metadata":` + meta + `}`, false},
+		{"timeline is not read", AAR_DATA_PREFIX + `{"metadata":` + meta + `,"timeline":[[[broken`, false},
+		{"no metadata", `{"timeline":[]}`, true},
+		{"not an object", AAR_DATA_PREFIX + `[1,2]`, true},
+		{"broken metadata", `{"metadata":{"island":`, true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeArchiveMetadata(strings.NewReader(tt.in))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m := got.Metadata; m.Terrain != "Altis" || m.Name != "CO10 Test" || m.Date != "2024-11-22" {
+				t.Errorf("decoded %+v", m)
+			}
+		})
+	}
+}
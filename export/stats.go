@@ -0,0 +1,90 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/10Dozen/ts_aar_parser/aar"
+)
+
+const (
+	STATS_JSON_SUFFIX    string = ".stats.json"
+	STATS_CSV_SUFFIX            = ".stats.csv"
+	FRIENDLY_FIRE_SUFFIX        = ".friendlyfire.json"
+)
+
+var statsCSVHeader = []string{
+	"name", "side", "group", "role", "rank",
+	"time_alive", "death_time", "deaths", "kills", "shots", "distance", "vehicle_time",
+}
+
+// Writes player statistics next to AAR archive at `archivePath`: `<archive>.stats.json` and `<archive>.stats.csv`.
+func WritePlayerStats(archivePath string, stats []*aar.PlayerStats) error {
+	base := strings.TrimSuffix(archivePath, ".zip")
+
+	content, err := json.MarshalIndent(stats, "", "    ")
+	if err != nil {
+		return err
+	}
+	err = WriteFileAtomic(base+STATS_JSON_SUFFIX, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(base+STATS_CSV_SUFFIX, func(w io.Writer) error {
+		return writeStatsCSV(w, stats)
+	})
+}
+
+func writeStatsCSV(w io.Writer, stats []*aar.PlayerStats) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(statsCSVHeader); err != nil {
+		return err
+	}
+	for _, ps := range stats {
+		deathTime := ""
+		if ps.DeathTime != aar.NoDeath {
+			deathTime = strconv.Itoa(ps.DeathTime)
+		}
+		record := []string{
+			ps.Name, ps.Side, ps.Group, ps.Role, ps.Rank,
+			strconv.Itoa(ps.TimeAlive),
+			deathTime,
+			strconv.Itoa(ps.Deaths),
+			strconv.Itoa(ps.Kills),
+			strconv.Itoa(ps.Shots),
+			strconv.FormatFloat(ps.Distance, 'f', 1, 64),
+			strconv.Itoa(ps.VehicleTime),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Friendly fire incidents of exported AAR.
+type FriendlyFireReport struct {
+	Title     string
+	Link      string
+	Incidents []*aar.FriendlyFire
+}
+
+// Writes friendly fire incidents next to AAR archive at `archivePath`: `<archive>.friendlyfire.json`.
+func WriteFriendlyFire(archivePath string, incidents []*aar.FriendlyFire) error {
+	content, err := json.MarshalIndent(incidents, "", "    ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(strings.TrimSuffix(archivePath, ".zip")+FRIENDLY_FIRE_SUFFIX, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
+}
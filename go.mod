@@ -1,3 +1,5 @@
 module github.com/10Dozen/ts_aar_parser
 
 go 1.23.0
+
+require github.com/klauspost/compress v1.18.0
@@ -0,0 +1,57 @@
+// Package linereader reads text lines of any length, unlike bufio.Scanner with it's 64 KB token limit.
+package linereader
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+const (
+	READ_BUFFER_SIZE int = 64 * 1024
+)
+
+// Line reader. Lines longer than `MaxLength` bytes are cut to `MaxLength`
+// and the rest of the line is discarded. Zero `MaxLength` means no limit.
+type Reader struct {
+	MaxLength int
+
+	r    *bufio.Reader
+	line []byte
+}
+
+func New(r io.Reader, maxLength int) *Reader {
+	return &Reader{
+		MaxLength: maxLength,
+		r:         bufio.NewReaderSize(r, READ_BUFFER_SIZE),
+	}
+}
+
+// Reads next line without line ending. `truncated` is true if line was longer than `MaxLength`.
+// Returns `io.EOF` when there are no more lines.
+func (lr *Reader) Next() (line string, truncated bool, err error) {
+	lr.line = lr.line[:0]
+	for {
+		chunk, isPrefix, err := lr.r.ReadLine()
+		if err != nil {
+			if err == io.EOF && (len(lr.line) > 0 || truncated) {
+				break
+			}
+			return "", false, err
+		}
+
+		if !truncated {
+			if lr.MaxLength > 0 && len(lr.line)+len(chunk) > lr.MaxLength {
+				chunk = chunk[:lr.MaxLength-len(lr.line)]
+				truncated = true
+			}
+			lr.line = append(lr.line, chunk...)
+		}
+
+		if !isPrefix {
+			break
+		}
+	}
+
+	return string(bytes.TrimSuffix(lr.line, []byte{'\r'})), truncated, nil
+}
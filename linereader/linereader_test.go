@@ -0,0 +1,132 @@
+package linereader
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+type line struct {
+	text      string
+	truncated bool
+}
+
+var long = strings.Repeat("x", READ_BUFFER_SIZE+10)
+
+var readerTests = []struct {
+	name      string
+	in        string
+	maxLength int
+	want      []line
+}{
+	{"lines", "a\nbb\n", 0, []line{{"a", false}, {"bb", false}}},
+	{"CRLF", "a\r\nbb\r\n\r\n", 0, []line{{"a", false}, {"bb", false}, {"", false}}},
+	{"cut off at EOF", "a\nb", 0, []line{{"a", false}, {"b", false}}},
+	{"cut off at EOF after CR", "a\r\nb\r", 0, []line{{"a", false}, {"b", false}}},
+	{"over the limit", "abcdef\nab\n", 4, []line{{"abcd", true}, {"ab", false}}},
+	{"at the limit", "abcd\nabcd\r\nab\n", 4, []line{{"abcd", false}, {"abcd", false}, {"ab", false}}},
+	{"over the limit at EOF", "ab\nabcdef", 4, []line{{"ab", false}, {"abcd", true}}},
+	{"longer than buffer", long + "\r\nab\n", 0, []line{{long, false}, {"ab", false}}},
+	{"longer than buffer over the limit", long + "\nab\n", 10, []line{{long[:10], true}, {"ab", false}}},
+}
+
+func TestReader(t *testing.T) {
+	for _, tt := range readerTests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(strings.NewReader(tt.in), tt.maxLength)
+			got := make([]line, 0)
+			for {
+				text, truncated, err := r.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, line{text, truncated})
+			}
+			assertLines(t, got, tt.want)
+		})
+	}
+}
+
+func TestTail(t *testing.T) {
+	for _, tt := range readerTests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewTail(strings.NewReader(tt.in), tt.maxLength)
+			got := make([]line, 0)
+			for {
+				text, truncated, err := r.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, line{text, truncated})
+			}
+			// -- Line cut off at EOF is kept until the file is not written anymore
+			if !strings.HasSuffix(tt.in, "\n") {
+				if len(got) != len(tt.want)-1 {
+					t.Fatalf("incomplete line returned by Next: %v", got)
+				}
+				text, truncated, ok := r.Rest()
+				if !ok {
+					t.Fatal("incomplete line is not returned by Rest")
+				}
+				got = append(got, line{text, truncated})
+			}
+			if _, _, ok := r.Rest(); ok {
+				t.Error("Rest returned a line after all lines are read")
+			}
+			assertLines(t, got, tt.want)
+		})
+	}
+}
+
+// Incomplete line is completed when the rest of it is written.
+func TestTailGrowingFile(t *testing.T) {
+	pr, pw := io.Pipe()
+	r := NewTail(&eofReader{pr}, 0)
+	go func() {
+		pw.Write([]byte("ab"))
+		pw.Write([]byte("cd\r\n"))
+		pw.Close()
+	}()
+
+	for {
+		text, truncated, err := r.Next()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil || text != "abcd" || truncated {
+			t.Errorf("Next() = %q, %v, %v", text, truncated, err)
+		}
+		return
+	}
+}
+
+// Returns `io.EOF` after every read, like a file that is being written.
+type eofReader struct {
+	r io.Reader
+}
+
+func (e *eofReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	if err == nil {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func assertLines(t *testing.T, got, want []line) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("line %d: got %.20q, %v, want %.20q, %v", i+1, got[i].text, got[i].truncated, want[i].text, want[i].truncated)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package linereader
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Line reader for a file that is still being written. Unlike `Reader`, incomplete last line
+// is not returned at EOF, it's kept until the rest of the line and line ending are written.
+type Tail struct {
+	MaxLength int
+
+	r         *bufio.Reader
+	line      []byte
+	truncated bool
+}
+
+func NewTail(r io.Reader, maxLength int) *Tail {
+	return &Tail{
+		MaxLength: maxLength,
+		r:         bufio.NewReaderSize(r, READ_BUFFER_SIZE),
+	}
+}
+
+// Reads next complete line without line ending. `truncated` is true if line was longer than `MaxLength`.
+// Returns `io.EOF` when there are no complete lines yet, reading may be retried once file grows.
+func (t *Tail) Next() (line string, truncated bool, err error) {
+	for {
+		chunk, err := t.r.ReadSlice('\n')
+		if !t.truncated {
+			// -- Line ending doesn't count towards the limit
+			if t.MaxLength > 0 && len(t.line)+len(bytes.TrimRight(chunk, "\r\n")) > t.MaxLength {
+				chunk = chunk[:t.MaxLength-len(t.line)]
+				t.truncated = true
+			}
+			t.line = append(t.line, chunk...)
+		}
+
+		switch err {
+		case nil:
+			return t.take()
+		case bufio.ErrBufferFull:
+			continue
+		default:
+			return "", false, err
+		}
+	}
+}
+
+// Returns incomplete last line, e.g. when the file is not written anymore. `ok` is false if there is none.
+func (t *Tail) Rest() (line string, truncated bool, ok bool) {
+	if len(t.line) == 0 && !t.truncated {
+		return "", false, false
+	}
+	line, truncated, _ = t.take()
+	return line, truncated, true
+}
+
+func (t *Tail) take() (string, bool, error) {
+	line := string(bytes.TrimRight(t.line, "\r\n"))
+	truncated := t.truncated
+	t.line, t.truncated = t.line[:0], false
+	return line, truncated, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	MEMORY_SAMPLE_INTERVAL time.Duration = 100 * time.Millisecond
+
+	METRIC_MEMORY_TOTAL    string = "/memory/classes/total:bytes"
+	METRIC_MEMORY_RELEASED        = "/memory/classes/heap/released:bytes"
+)
+
+// Samples memory held by the process and remembers the peak value.
+type memoryMonitor struct {
+	peak     atomic.Uint64
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Sets Go soft memory limit from `MemoryLimit` config key. GC runs more often as the limit is approached.
+func applyMemoryLimit() {
+	if configuration.MemoryLimit > 0 {
+		debug.SetMemoryLimit(int64(configuration.MemoryLimit) << 20)
+	}
+}
+
+func startMemoryMonitor() *memoryMonitor {
+	m := &memoryMonitor{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(MEMORY_SAMPLE_INTERVAL)
+		defer ticker.Stop()
+		for {
+			m.sample()
+			select {
+			case <-m.stop:
+				m.sample()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return m
+}
+
+// Stops sampling and returns peak memory in bytes. May be called several times.
+func (m *memoryMonitor) Stop() uint64 {
+	m.stopOnce.Do(func() { close(m.stop) })
+	<-m.done
+	return m.peak.Load()
+}
+
+// Memory mapped by Go runtime, without heap memory returned to OS.
+func (m *memoryMonitor) sample() {
+	samples := []metrics.Sample{
+		{Name: METRIC_MEMORY_TOTAL},
+		{Name: METRIC_MEMORY_RELEASED},
+	}
+	metrics.Read(samples)
+	used := samples[0].Value.Uint64() - samples[1].Value.Uint64()
+	if used > m.peak.Load() {
+		m.peak.Store(used)
+	}
+}
@@ -0,0 +1,279 @@
+package orbat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/10Dozen/ts_aar_parser/parseerr"
+)
+
+const (
+	Private    string = "PRIVATE"
+	Corporal          = "CORPORAL"
+	Sergeant          = "SERGEANT"
+	Lieutenant        = "LIEUTENANT"
+
+	NoSlot string = ""
+	SlotHQ        = "HQ"
+	SlotSL        = "SL"
+	SlotTL        = "TL"
+)
+
+type ORBAT struct {
+	Mission string
+	Leaders *Leaders
+	Sides   map[string]*Side
+
+	Source string `json:"-"` // name of the RPT file ORBAT was found in
+	Line   int    `json:"-"` // RPT line number of the ORBAT header
+
+	sideOrder []string // side names in output order
+}
+
+func (o *ORBAT) MarshalJSON() ([]byte, error) {
+	out, err := json.Marshal(struct {
+		ORBAT
+		Sides []*Side
+	}{ORBAT: *o, Sides: o.SideList()})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type Leaders struct {
+	HQ           []*Leader
+	SquadLeaders []*Leader
+	TeamLeaders  []*Leader
+}
+
+type Side struct {
+	Name   string
+	Groups map[string]*Group
+
+	groupOrder []string // group names in output order
+}
+
+func (s *Side) MarshalJSON() ([]byte, error) {
+	out, err := json.Marshal(struct {
+		Side
+		Groups []*Group
+	}{Side: *s, Groups: s.GroupList()})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type Group struct {
+	Name  string
+	Units []*Unit
+}
+
+type Leader struct {
+	Group string
+	Role  string
+	Name  string
+	side  string
+}
+
+type Unit struct {
+	Role  string
+	Rank  string
+	Name  string
+	side  string
+	group string
+}
+
+func (l *Leader) Side() string {
+	return l.side
+}
+
+func (u *Unit) Side() string {
+	return u.side
+}
+
+func (u *Unit) Group() string {
+	return u.group
+}
+
+// Returns unit with given name, nil if there is none.
+func (o *ORBAT) Unit(name string) *Unit {
+	for _, side := range o.SideList() {
+		for _, group := range side.GroupList() {
+			for _, u := range group.Units {
+				if u.Name == name {
+					return u
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Returns all units in output order of sides and groups.
+func (o *ORBAT) Units() []*Unit {
+	units := make([]*Unit, 0)
+	for _, side := range o.SideList() {
+		for _, group := range side.GroupList() {
+			units = append(units, group.Units...)
+		}
+	}
+	return units
+}
+
+type Handler struct {
+	orbats []*ORBAT
+}
+
+// Checks given RPT line for ORBAT header or ORBAT unit data and saves it.
+// Returns `*parseerr.Error` if line is malformed, `num` is line number used in the error.
+func (oh *Handler) ParseLine(num int, line string) error {
+	if !patterns.Test.MatchString(line) {
+		return nil
+	}
+	if !strings.HasSuffix(strings.TrimSpace(line), `"`) {
+		return parseerr.New(num, line, parseerr.ErrTruncated)
+	}
+
+	// -- Check for ORBAT Metadata
+	matches := patterns.Metadata.FindStringSubmatch(line)
+	if matches != nil {
+		missionName := matches[1]
+		orbat := &ORBAT{
+			Mission: missionName,
+			Line:    num,
+			Leaders: &Leaders{
+				HQ:           make([]*Leader, 0),
+				SquadLeaders: make([]*Leader, 0),
+				TeamLeaders:  make([]*Leader, 0),
+			},
+			Sides: make(map[string]*Side, 0),
+		}
+
+		if oh.orbats == nil {
+			oh.orbats = make([]*ORBAT, 1)
+			oh.orbats[0] = orbat
+			return nil
+		}
+
+		oh.orbats = append(oh.orbats, orbat)
+		return nil
+	}
+
+	// -- Check for ORBAT data line
+	matches = patterns.Data.FindStringSubmatch(line)
+	if matches == nil || len(matches) < 2 {
+		return nil
+	}
+
+	if len(oh.orbats) == 0 {
+		return parseerr.New(num, line, parseerr.ErrNoMetadata)
+	}
+	unit, err := oh.parseUnit(matches[1])
+	if err != nil {
+		return parseerr.New(num, line, err)
+	}
+	orbat := oh.orbats[len(oh.orbats)-1]
+	oh.addUnit(unit, orbat)
+	return nil
+}
+
+// Returns ORBATs found so far.
+func (oh *Handler) ORBATs() []*ORBAT {
+	return oh.orbats
+}
+
+func (oh *Handler) parseUnit(line string) (Unit, error) {
+	elements := make([]string, 0, 5)
+	if err := json.Unmarshal(
+		[]byte(strings.ReplaceAll(line, `""`, `"`)),
+		&elements,
+	); err != nil {
+		return Unit{}, fmt.Errorf("%w: %w", parseerr.ErrMalformed, err)
+	}
+	if len(elements) < 5 {
+		return Unit{}, fmt.Errorf("%w: expected 5 unit fields, got %d", parseerr.ErrMalformed, len(elements))
+	}
+
+	return Unit{
+		side:  elements[0],
+		group: elements[1],
+		Role:  elements[2],
+		Rank:  elements[3],
+		Name:  elements[4],
+	}, nil
+}
+
+func (oh *Handler) addUnit(unit Unit, orbat *ORBAT) {
+	side, ok := orbat.Sides[unit.side]
+	if !ok {
+		side = &Side{
+			Name:   unit.side,
+			Groups: make(map[string]*Group, 0),
+		}
+		orbat.Sides[unit.side] = side
+		orbat.sideOrder = append(orbat.sideOrder, side.Name)
+	}
+
+	group, ok := side.Groups[unit.group]
+	if !ok {
+		group = &Group{
+			Name:  unit.group,
+			Units: make([]*Unit, 0),
+		}
+		side.Groups[unit.group] = group
+		side.groupOrder = append(side.groupOrder, group.Name)
+	}
+	group.Units = append(group.Units, &unit)
+
+	// -- Add leaders if rank is above Private
+	leader := &Leader{
+		Role:  unit.Role,
+		Name:  unit.Name,
+		Group: group.Name,
+		side:  side.Name,
+	}
+	switch LeaderSlot(unit.Rank) {
+	case NoSlot:
+		return
+	case SlotTL:
+		orbat.Leaders.TeamLeaders = append(
+			orbat.Leaders.TeamLeaders,
+			leader,
+		)
+	case SlotSL:
+		orbat.Leaders.SquadLeaders = append(
+			orbat.Leaders.SquadLeaders,
+			leader,
+		)
+	default:
+		orbat.Leaders.HQ = append(
+			orbat.Leaders.HQ,
+			leader,
+		)
+	}
+}
+
+// Returns leader slot of the rank: Corporal is team leader, Sergeant is squad leader, higher ranks are HQ.
+// Private is not a leader.
+func LeaderSlot(rank string) string {
+	switch rank {
+	case Private:
+		return NoSlot
+	case Corporal:
+		return SlotTL
+	case Sergeant:
+		return SlotSL
+	}
+	return SlotHQ
+}
+
+func NewHandler() *Handler {
+	h := &Handler{
+		orbats: make([]*ORBAT, 0),
+	}
+
+	return h
+}
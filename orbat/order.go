@@ -0,0 +1,105 @@
+package orbat
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Order of sides, groups and leaders in ORBAT output.
+type Order int
+
+const (
+	OrderAppearance Order = iota // order of first appearance in RPT
+	OrderNatural                 // natural sort by name, e.g. "1`2" goes before "1`10"
+)
+
+// Parses order name used in CLI: `appearance` or `natural`.
+func ParseOrder(name string) (Order, bool) {
+	switch strings.ToLower(name) {
+	case "appearance", "":
+		return OrderAppearance, true
+	case "natural":
+		return OrderNatural, true
+	}
+	return OrderAppearance, false
+}
+
+// Returns sides in output order.
+func (o *ORBAT) SideList() []*Side {
+	sides := make([]*Side, 0, len(o.sideOrder))
+	for _, name := range o.sideOrder {
+		sides = append(sides, o.Sides[name])
+	}
+	return sides
+}
+
+// Returns groups in output order.
+func (s *Side) GroupList() []*Group {
+	groups := make([]*Group, 0, len(s.groupOrder))
+	for _, name := range s.groupOrder {
+		groups = append(groups, s.Groups[name])
+	}
+	return groups
+}
+
+// Sorts sides, groups and leaders. Leaders follow the order of their sides and groups,
+// leaders of the same group keep RPT order.
+// Sorting by `OrderAppearance` is a no-op, ORBAT is always collected in that order.
+func (o *ORBAT) Sort(order Order) {
+	if order != OrderNatural {
+		return
+	}
+
+	slices.SortStableFunc(o.sideOrder, NaturalCompare)
+	for _, side := range o.Sides {
+		slices.SortStableFunc(side.groupOrder, NaturalCompare)
+	}
+
+	rank := make(map[[2]string]int)
+	for _, side := range o.SideList() {
+		for _, group := range side.GroupList() {
+			rank[[2]string{side.Name, group.Name}] = len(rank)
+		}
+	}
+	byGroup := func(a, b *Leader) int {
+		return rank[[2]string{a.side, a.Group}] - rank[[2]string{b.side, b.Group}]
+	}
+	slices.SortStableFunc(o.Leaders.HQ, byGroup)
+	slices.SortStableFunc(o.Leaders.SquadLeaders, byGroup)
+	slices.SortStableFunc(o.Leaders.TeamLeaders, byGroup)
+}
+
+// Compares strings treating digit sequences as numbers.
+func NaturalCompare(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if unicode.IsDigit(ra[i]) && unicode.IsDigit(rb[j]) {
+			si, sj := i, j
+			for i < len(ra) && unicode.IsDigit(ra[i]) {
+				i++
+			}
+			for j < len(rb) && unicode.IsDigit(rb[j]) {
+				j++
+			}
+			na := strings.TrimLeft(string(ra[si:i]), "0")
+			nb := strings.TrimLeft(string(rb[sj:j]), "0")
+			if len(na) != len(nb) {
+				return len(na) - len(nb)
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			continue
+		}
+
+		ca, cb := unicode.ToLower(ra[i]), unicode.ToLower(rb[j])
+		if ca != cb {
+			return int(ca) - int(cb)
+		}
+		i++
+		j++
+	}
+	return (len(ra) - i) - (len(rb) - j)
+}
@@ -0,0 +1,94 @@
+package orbat
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestNaturalCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int // sign of the result
+	}{
+		{"1`2", "1`10", -1},
+		{"1`10", "1`2", 1},
+		{"Alpha 2", "Alpha 10", -1},
+		{"Alpha 9", "Alpha 09", 0},
+		{"Alpha 010", "Alpha 9", 1},
+		{"2`1", "10`1", -1},
+		{"1`1", "1`1", 0},
+		{"alpha", "Alpha", 0},
+		{"Alpha", "Bravo", -1},
+		{"Alpha", "Alpha 1", -1},
+		{"Alpha 1", "Alpha 1a", -1},
+		{"Alpha 1b", "Alpha 1a", 1},
+		{"Squad 99999999999999999999", "Squad 100000000000000000000", -1},
+		{"", "1", -1},
+		{"Отделение 2", "Отделение 10", -1},
+	}
+	for _, tt := range tests {
+		if got := sign(NaturalCompare(tt.a, tt.b)); got != tt.want {
+			t.Errorf("NaturalCompare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSortNatural(t *testing.T) {
+	h := NewHandler()
+	lines := []string{`"[tS_ORBAT] Meta: CO10 Test"`}
+	for _, u := range [][3]string{
+		{"OPFOR", "1`10", "COLONEL"},
+		{"BLUFOR", "1`10", Corporal},
+		{"BLUFOR", "1`2", Corporal},
+		{"BLUFOR", "1`2", Private},
+		{"BLUFOR", "1`1", Sergeant},
+		{"BLUFOR", "10`1", Lieutenant},
+		{"BLUFOR", "2`1", Lieutenant},
+	} {
+		lines = append(lines, fmt.Sprintf(`"[tS_ORBAT] [""%s"", ""%s"", ""Role"", ""%s"", ""%s %s""]"`, u[0], u[1], u[2], u[0], u[1]))
+	}
+	for i, line := range lines {
+		if err := h.ParseLine(i+1, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o := h.ORBATs()[0]
+
+	o.Sort(OrderAppearance)
+	if got := groupNames(o); !slices.Equal(got, []string{"OPFOR 1`10", "BLUFOR 1`10", "BLUFOR 1`2", "BLUFOR 1`1", "BLUFOR 10`1", "BLUFOR 2`1"}) {
+		t.Errorf("appearance order %q", got)
+	}
+
+	o.Sort(OrderNatural)
+	if got := groupNames(o); !slices.Equal(got, []string{"BLUFOR 1`1", "BLUFOR 1`2", "BLUFOR 1`10", "BLUFOR 2`1", "BLUFOR 10`1", "OPFOR 1`10"}) {
+		t.Errorf("natural order %q", got)
+	}
+	leaders := func(ls []*Leader) []string {
+		names := make([]string, 0, len(ls))
+		for _, l := range ls {
+			names = append(names, l.Name)
+		}
+		return names
+	}
+	if got := leaders(o.Leaders.HQ); !slices.Equal(got, []string{"BLUFOR 2`1", "BLUFOR 10`1", "OPFOR 1`10"}) {
+		t.Errorf("HQ %q", got)
+	}
+	if got := leaders(o.Leaders.TeamLeaders); !slices.Equal(got, []string{"BLUFOR 1`2", "BLUFOR 1`10"}) {
+		t.Errorf("team leaders %q", got)
+	}
+}
+
+func groupNames(o *ORBAT) []string {
+	names := make([]string, 0)
+	for _, side := range o.SideList() {
+		for _, group := range side.GroupList() {
+			names = append(names, side.Name+" "+group.Name)
+		}
+	}
+	return names
+}
+
+func sign(n int) int {
+	return min(max(n, -1), 1)
+}
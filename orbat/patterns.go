@@ -0,0 +1,24 @@
+package orbat
+
+import "regexp"
+
+const (
+	METADATA_PATTERN string = `"\[tS_ORBAT\] Meta: (.*)"`
+	DATA_PATTERN     string = `"\[tS_ORBAT\] (\[.*\])"`
+	TEST_PATTERN     string = `"\[tS_ORBAT\] (?:Meta: |\[)`
+)
+
+// Compiled ORBAT line patterns.
+type Patterns struct {
+	Test, Metadata, Data *regexp.Regexp
+}
+
+var patterns = NewPatterns()
+
+func NewPatterns() *Patterns {
+	return &Patterns{
+		Test:     regexp.MustCompile(TEST_PATTERN),
+		Metadata: regexp.MustCompile(METADATA_PATTERN),
+		Data:     regexp.MustCompile(DATA_PATTERN),
+	}
+}
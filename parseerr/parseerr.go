@@ -0,0 +1,87 @@
+// Package parseerr holds errors reported for malformed RPT lines.
+package parseerr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	SNIPPET_LENGTH int = 120
+)
+
+// What to do when malformed line is found.
+type Policy int
+
+const (
+	Skip  Policy = iota // skip line, report it and continue
+	Abort               // stop parsing and return the error
+)
+
+var (
+	ErrMalformed  = errors.New("malformed line")
+	ErrNoMetadata = errors.New("data line without preceding metadata")
+	ErrTooLong    = errors.New("line exceeds max line length")
+	ErrTruncated  = errors.New("line is truncated by Arma, closing tag is missing")
+)
+
+// Error of a single RPT line. `File` and `Line` point to the line in source RPT file.
+type Error struct {
+	File    string
+	Line    int
+	Snippet string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %v (%s)", e.File, e.Line, e.Err, e.Snippet)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Creates error for given line. Long lines are shortened to `SNIPPET_LENGTH` runes.
+func New(line int, content string, err error) *Error {
+	return &Error{
+		Line:    line,
+		Snippet: Snippet(content),
+		Err:     err,
+	}
+}
+
+// Shortens line content for error messages.
+func Snippet(content string) string {
+	content = strings.TrimSpace(content)
+	runes := []rune(content)
+	if len(runes) <= SNIPPET_LENGTH {
+		return content
+	}
+	return string(runes[:SNIPPET_LENGTH]) + "..."
+}
+
+// List of skipped lines.
+type List []*Error
+
+func (l List) Error() string {
+	lines := make([]string, 0, len(l))
+	for _, e := range l {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Either saves error to the list (`Skip`) or returns it back (`Abort`).
+// Errors other than `*Error` are always returned.
+func (l *List) Handle(err error, policy Policy) error {
+	var lineErr *Error
+	if err == nil {
+		return nil
+	}
+	if !errors.As(err, &lineErr) || policy == Abort {
+		return err
+	}
+	*l = append(*l, lineErr)
+	return nil
+}
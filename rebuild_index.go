@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/10Dozen/ts_aar_parser/export"
+)
+
+// Runs `rebuild-index` command: regenerates aarListConfig.ini from AAR archives in AARDirectory.
+func runRebuildIndex(args []string) int {
+	opts := &CLIOptions{}
+	var dryRun bool
+
+	fs := flag.NewFlagSet(CMD_REBUILD_INDEX, flag.ContinueOnError)
+	fs.StringVar(&opts.ConfigFile, "config", defaultConfigFile(), "путь к файлу конфига (env "+ENV_CONFIG+")")
+	fs.StringVar(&opts.Profile, "profile", defaultProfile(), "`имя` профиля конфига (env "+ENV_PROFILE+")")
+	fs.StringVar(&opts.Out, "out", "", "директория для экспорта (заменяет AARDirectory)")
+	fs.BoolVar(&dryRun, "dry-run", false, "только вывести отчет, не записывая aarListConfig.ini")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Использование: ts_aar_parser %s [флаги]\n\nФлаги:\n", CMD_REBUILD_INDEX)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return EXIT_OK
+		}
+		return EXIT_USAGE
+	}
+
+	if err := loadConfiguration(opts, false); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		return EXIT_FAILURE
+	}
+	if err := checkDirectory("AARDirectory", configuration.AARDirectory); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		return EXIT_FAILURE
+	}
+
+	report, err := export.RebuildAARListConfig(configuration.AARDirectory, configuration.naming)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		return EXIT_FAILURE
+	}
+
+	fmt.Printf("Найдено AAR архивов: %d\n", len(report.Entries))
+	if len(report.Unindexed) > 0 {
+		fmt.Printf("\nАрхивы без записи в конфиге (%d):\n", len(report.Unindexed))
+		for _, link := range report.Unindexed {
+			fmt.Printf("  - %s\n", link)
+		}
+	}
+	if len(report.Missing) > 0 {
+		fmt.Printf("\nЗаписи конфига без архива (%d):\n", len(report.Missing))
+		for _, e := range report.Missing {
+			fmt.Printf("  - %s %s (%s)\n", e.Date, e.Title, e.Link)
+		}
+	}
+	if len(report.Broken) > 0 {
+		fmt.Printf("\nНечитаемые архивы (%d):\n", len(report.Broken))
+		for link, err := range report.Broken {
+			fmt.Printf("  - %s: %v\n", link, err)
+		}
+	}
+
+	if dryRun {
+		return EXIT_OK
+	}
+
+	cfgPath := filepath.Join(configuration.AARDirectory, export.AAR_CONFIG_FILENAME)
+	if err := export.WriteAARListConfig(cfgPath, report.Entries); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		return EXIT_FAILURE
+	}
+	fmt.Printf("\nКонфиг AAR пересоздан: %s\n", cfgPath)
+	return EXIT_OK
+}
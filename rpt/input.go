@@ -0,0 +1,241 @@
+package rpt
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	GZIP_SUFFIX string = ".gz"
+	ZSTD_SUFFIX        = ".zst"
+	ZIP_SUFFIX         = ".zip"
+
+	HEADER_PEEK_SIZE int = 64 * 1024
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Checks that file name is RPT file, plain or compressed: `.rpt`, `.rpt.gz`, `.rpt.zst`.
+func IsRPTName(name string) bool {
+	name = strings.ToLower(name)
+	name = strings.TrimSuffix(name, GZIP_SUFFIX)
+	name = strings.TrimSuffix(name, ZSTD_SUFFIX)
+	return strings.HasSuffix(name, RPT_SUFFIX)
+}
+
+// Checks that file name is zip archive that may contain RPT files.
+func IsArchiveName(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ZIP_SUFFIX)
+}
+
+// Name of the RPT file used in output and errors: file name, or `<archive>/<file>` for RPT inside of zip archive.
+func (f *File) Name() string {
+	if f.Entry != "" {
+		return filepath.Base(f.Path) + "/" + f.Entry
+	}
+	return filepath.Base(f.Path)
+}
+
+// Opens RPT file for reading. Files inside of zip archive and gzip or zstd compressed files are decompressed.
+// RPT made by `FromReader` can be opened only once.
+func (f *File) Open() (io.ReadCloser, error) {
+	if f.reader != nil {
+		r := f.reader
+		f.reader = eofReader{}
+		return r, nil
+	}
+
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	if f.Entry == "" {
+		return decompress(file, file)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	archive, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", f.Path, err)
+	}
+	entry, err := archive.Open(f.Entry)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", f.Path, err)
+	}
+	return decompress(entry, multiCloser{entry, file})
+}
+
+// Returns RPT read from `r`, e.g. from stdin or network stream, named `name` in output and errors.
+// Compressed content is decompressed. Report date is taken from `Current time:` line of the RPT header,
+// if there is none - current date is used. Content is read only once, by the first `Open` call.
+func FromReader(name string, r io.Reader) (*File, error) {
+	rc, err := decompress(r, io.NopCloser(r))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	// -- Header is peeked, so it's read again by the parser
+	buffered := bufio.NewReaderSize(rc, HEADER_PEEK_SIZE)
+	header, err := buffered.Peek(HEADER_PEEK_SIZE)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	date, err := readHeaderDate(bytes.NewReader(header))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	now := time.Now()
+	if date == "" {
+		date = now.Format(DATE_FORMAT)
+	}
+	return &File{
+		Path:    name,
+		Date:    date,
+		ModTime: now,
+		reader:  readCloser{buffered, rc},
+	}, nil
+}
+
+// Returns RPT files inside of zip archive at `path`. Report date is taken from the file name,
+// then from `Current time:` line of the RPT header and then from file modification time in archive.
+func StatArchive(path string) ([]*File, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	defer archive.Close()
+
+	files := make([]*File, 0)
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() || !IsRPTName(entry.Name) {
+			continue
+		}
+
+		file := &File{
+			Path:    path,
+			Entry:   entry.Name,
+			ModTime: entry.Modified,
+		}
+		if err := file.detectDate(); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// Returns RPT files at `path`: the file itself or RPT files inside of zip archive.
+func StatAll(path string) ([]*File, error) {
+	if IsArchiveName(path) {
+		return StatArchive(path)
+	}
+	file, err := Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return []*File{file}, nil
+}
+
+// Sets report date from file name, RPT header or modification time.
+func (f *File) detectDate() error {
+	f.Date = DateFromFilename(filepath.Base(f.Entry))
+	if f.Entry == "" {
+		f.Date = DateFromFilename(filepath.Base(f.Path))
+	}
+	if IsDate(f.Date) {
+		return nil
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	f.Date, err = readHeaderDate(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Name(), err)
+	}
+	if f.Date == "" {
+		f.Date = f.ModTime.Format(DATE_FORMAT)
+	}
+	return nil
+}
+
+// Wraps reader with gzip or zstd decompression, detected by stream header.
+// `closer` is closed when returned reader is closed.
+func decompress(r io.Reader, closer io.Closer) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	magic, _ := buffered.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			closer.Close()
+			return nil, err
+		}
+		return readCloser{gz, multiCloser{gz, closer}}, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(buffered)
+		if err != nil {
+			closer.Close()
+			return nil, err
+		}
+		return readCloser{zr, multiCloser{zstdCloser{zr}, closer}}, nil
+	}
+	return readCloser{buffered, closer}, nil
+}
+
+// Content of already opened `FromReader` RPT.
+type eofReader struct{}
+
+func (eofReader) Read([]byte) (int, error) { return 0, io.EOF }
+func (eofReader) Close() error             { return nil }
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type multiCloser []io.Closer
+
+func (mc multiCloser) Close() error {
+	var first error
+	for _, c := range mc {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// zstd decoder is closed without error.
+type zstdCloser struct {
+	d *zstd.Decoder
+}
+
+func (c zstdCloser) Close() error {
+	c.d.Close()
+	return nil
+}
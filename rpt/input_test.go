@@ -0,0 +1,218 @@
+package rpt
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const testRPT = "=====================================================================\n" +
+	" Current time:  2024/11/21 22:00:00\n" +
+	"=====================================================================\n" +
+	"22:22:22 \"[tS_ORBAT] Meta: CO10 Test\"\n"
+
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(w, content); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zstded(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, content); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readAll(t *testing.T, f *File) string {
+	t.Helper()
+	r, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// Compression is detected by content, not by file extension.
+func TestOpenCompressed(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"plain.rpt": []byte(testRPT),
+		"arma3server_x64_2024-11-22_22-00-00.rpt.gz": gzipped(t, testRPT),
+		"zstd.rpt.zst":         zstded(t, testRPT),
+		"gzip_named_plain.rpt": gzipped(t, testRPT),
+		"empty.rpt":            nil,
+		"short.rpt":            {0x1f},
+	}
+	wantDate := map[string]string{
+		"arma3server_x64_2024-11-22_22-00-00.rpt.gz": "2024-11-22",
+		"empty.rpt": time.Now().Format(DATE_FORMAT),
+		"short.rpt": time.Now().Format(DATE_FORMAT),
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if !IsRPTName(name) {
+				t.Errorf("%s is not RPT name", name)
+			}
+
+			f, err := Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, ok := wantDate[name]
+			if !ok {
+				want = "2024-11-21"
+			}
+			if f.Date != want {
+				t.Errorf("date %s, want %s", f.Date, want)
+			}
+
+			wantContent := testRPT
+			if len(content) < len(gzipMagic) {
+				wantContent = string(content)
+			}
+			if got := readAll(t, f); got != wantContent {
+				t.Errorf("content %q", got)
+			}
+		})
+	}
+}
+
+func TestOpenBrokenCompressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.rpt.gz")
+	if err := os.WriteFile(path, gzipped(t, testRPT)[:20], 0644); err != nil {
+		t.Fatal(err)
+	}
+	f := &File{Path: path}
+	r, err := f.Open()
+	if err != nil {
+		return
+	}
+	defer r.Close()
+	if _, err := io.ReadAll(r); err == nil {
+		t.Error("expected error for truncated gzip stream")
+	}
+}
+
+func TestStatArchive(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rpts.zip")
+	modified := time.Date(2024, 10, 31, 22, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	entries := []struct {
+		name    string
+		content []byte
+	}{
+		{"arma3server_x64_2024-11-22_22-00-00.rpt", []byte("22:22:22 line\n")},
+		{"logs/", nil},
+		{"logs/header.RPT", []byte(testRPT)},
+		{"logs/undated.rpt.gz", gzipped(t, "22:22:22 line\n")},
+		{"logs/undated.rpt.zst", zstded(t, testRPT)},
+		{"readme.txt", []byte("not RPT")},
+	}
+	for _, e := range entries {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Modified: modified, Method: zip.Deflate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(e.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := StatAll(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(files))
+	for _, f := range files {
+		got = append(got, f.Name()+" "+f.Date)
+	}
+	want := []string{
+		"rpts.zip/arma3server_x64_2024-11-22_22-00-00.rpt 2024-11-22",
+		"rpts.zip/logs/header.RPT 2024-11-21",
+		"rpts.zip/logs/undated.rpt.gz 2024-10-31",
+		"rpts.zip/logs/undated.rpt.zst 2024-11-21",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("files:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if content := readAll(t, files[3]); content != testRPT {
+		t.Errorf("content of %s: %q", files[3].Name(), content)
+	}
+
+	// -- Archive is listed with other RPT files of the directory
+	if err := os.WriteFile(filepath.Join(dir, "arma3server_x64_2024-11-23_22-00-00.rpt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	listed, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != len(want)+1 || listed[len(listed)-1].Date != "2024-11-23" || listed[0].Date != "2024-10-31" {
+		t.Errorf("listed %d files", len(listed))
+	}
+}
+
+func TestFromReaderCompressed(t *testing.T) {
+	for name, content := range map[string][]byte{
+		"plain": []byte(testRPT),
+		"gzip":  gzipped(t, testRPT),
+		"zstd":  zstded(t, testRPT),
+	} {
+		f, err := FromReader("-", bytes.NewReader(content))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if f.Date != "2024-11-21" {
+			t.Errorf("%s: date %s", name, f.Date)
+		}
+		if got := readAll(t, f); got != testRPT {
+			t.Errorf("%s: content %q", name, got)
+		}
+		if got := readAll(t, f); got != "" {
+			t.Errorf("%s: content is read twice", name)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package rpt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/10Dozen/ts_aar_parser/aar"
+	"github.com/10Dozen/ts_aar_parser/linereader"
+	"github.com/10Dozen/ts_aar_parser/orbat"
+	"github.com/10Dozen/ts_aar_parser/parseerr"
+)
+
+const (
+	RPT_SUFFIX         string = ".rpt"
+	RPT_VERSION_SUFFIX string = "x64"
+
+	DEFAULT_MAX_LINE_LENGTH int = 64 * 1024 * 1024
+	CHECK_CANCEL_EVERY      int = 1000
+)
+
+// Content extracted from one or several RPT files.
+type Content struct {
+	Date    string         // date of parsed RPT file
+	AARs    []*aar.AAR     // list of AAR caches extracted from RPT
+	ORBATs  []*orbat.ORBAT // orbat from RPT
+	Skipped parseerr.List  // malformed lines skipped during parsing
+}
+
+// RPT parser. Feeds every RPT line to ORBAT and AAR handlers.
+type Parser struct {
+	TmpDir        string          // directory for AAR temporary files
+	InMemory      bool            // convert AARs while reading RPT instead of spooling them to `TmpDir`
+	Policy        parseerr.Policy // what to do with malformed lines
+	MaxLineLength int             // lines longer than this are reported and skipped, 0 - no limit
+	Workers       int             // max RPT files parsed at once by `ParseFiles`, 0 - no limit
+}
+
+// Creates RPT parser that spools AAR data into `tmpDir`.
+func NewParser(tmpDir string) *Parser {
+	return &Parser{
+		TmpDir:        tmpDir,
+		MaxLineLength: DEFAULT_MAX_LINE_LENGTH,
+	}
+}
+
+// Parses RPT content from given reader and returns found ORBATs and AARs.
+// `name` is used as a file name in errors.
+// AARs are linked to ORBATs (see `aar.LinkORBATs`), but not parsed yet, use `aar.ParseAll` or `AAR.Parse` to convert them.
+// On error or `ctx` cancellation all AAR temporary files are removed.
+func (p *Parser) Parse(ctx context.Context, name string, r io.Reader) (*Content, error) {
+	content := &Content{}
+
+	// -- Add thread local handlers
+	orbatHandler := orbat.NewHandler()
+	aarHandler := aar.NewHandler(p.TmpDir)
+	aarHandler.InMemory = p.InMemory
+
+	fail := func(err error) (*Content, error) {
+		aarHandler.Close()
+		aarHandler.Clear()
+		return nil, err
+	}
+
+	reader := linereader.New(r, p.MaxLineLength)
+	num := 0
+	for {
+		if num%CHECK_CANCEL_EVERY == 0 && ctx.Err() != nil {
+			return fail(ctx.Err())
+		}
+
+		line, truncated, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(fmt.Errorf("%s:%d: %w", name, num+1, err))
+		}
+		num++
+
+		errs := []error{parseerr.New(num, line, parseerr.ErrTooLong)}
+		if !truncated {
+			errs = []error{
+				orbatHandler.ParseLine(num, line),
+				aarHandler.ParseLine(num, line),
+			}
+		}
+		for _, err := range errs {
+			var lineErr *parseerr.Error
+			if errors.As(err, &lineErr) {
+				lineErr.File = name
+			}
+			if err = content.Skipped.Handle(err, p.Policy); err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	if err := aarHandler.Close(); err != nil {
+		return fail(err)
+	}
+
+	content.AARs = aarHandler.AARs()
+	content.ORBATs = orbatHandler.ORBATs()
+	for _, a := range content.AARs {
+		a.Source = name
+	}
+	for _, o := range content.ORBATs {
+		o.Source = name
+	}
+	aar.LinkORBATs(content.AARs, content.ORBATs)
+
+	return content, nil
+}
+
+// Parses RPT file, compressed or inside of zip archive. Content date is the report date of the file (see `Stat`).
+func (p *Parser) ParseFile(ctx context.Context, file *File) (*Content, error) {
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	content, err := p.Parse(ctx, file.Name(), r)
+	if err != nil {
+		return nil, err
+	}
+	content.Date = file.Date
+	for _, a := range content.AARs {
+		a.Date = content.Date
+	}
+
+	return content, nil
+}
+
+type parseResult struct {
+	content *Content
+	err     error
+}
+
+// Parses several RPT files in parallel, at most `Workers` at once, and merges their content.
+// If any file fails, temporary files of all files are removed and first error is returned.
+func (p *Parser) ParseFiles(ctx context.Context, date string, files []*File) (*Content, error) {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = len(files)
+	}
+	slots := make(chan struct{}, max(workers, 1))
+
+	// -- Process several .rpt file in parallel
+	channels := make([]chan parseResult, 0)
+	for _, v := range files {
+		ch := make(chan parseResult, 1)
+		channels = append(channels, ch)
+		go func() {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			content, err := p.ParseFile(ctx, v)
+			ch <- parseResult{content, err}
+		}()
+	}
+
+	// -- Gather data from goroutines into a single object
+	content := &Content{
+		Date:   date,
+		AARs:   make([]*aar.AAR, 0),
+		ORBATs: make([]*orbat.ORBAT, 0),
+	}
+	var firstErr error
+	for _, ch := range channels {
+		result := <-ch
+		if result.err != nil {
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			continue
+		}
+
+		fileContent := result.content
+		content.Date = fileContent.Date
+		content.AARs = append(content.AARs, fileContent.AARs...)
+		content.ORBATs = append(content.ORBATs, fileContent.ORBATs...)
+		content.Skipped = append(content.Skipped, fileContent.Skipped...)
+	}
+
+	if firstErr != nil {
+		aar.Clear(content.AARs)
+		return nil, firstErr
+	}
+	// -- ORBAT of the mission may be in another file of the date
+	aar.LinkORBATs(content.AARs, content.ORBATs)
+	return content, nil
+}
+
+// Returns date of the RPT file from it's name, e.g. `arma3server_x64_2024-11-21_22-00-00.rpt`
+func DateFromFilename(filename string) string {
+	parts := strings.Split(strings.ToLower(filename), "_")
+	if len(parts) < 3 {
+		return ""
+	}
+	filedate := parts[2]
+	if parts[1] != RPT_VERSION_SUFFIX {
+		filedate = parts[1]
+	}
+	return filedate
+}
+
+// Finds RPT files in directory with the latest report date.
+// Returns that date and the files.
+func FindLatest(path string) (string, []*File, error) {
+	files, err := List(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	latest := Latest(files)
+	if len(latest) == 0 {
+		return "", nil, nil
+	}
+	return latest[0].Date, latest, nil
+}
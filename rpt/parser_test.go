@@ -0,0 +1,106 @@
+package rpt
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/10Dozen/ts_aar_parser/parseerr"
+)
+
+const (
+	testORBATMeta = `22:22:22 "[tS_ORBAT] Meta: CO10 Test"`
+	testORBATUnit = `22:22:22 "[tS_ORBAT] [""BLUFOR"", ""1'1"", ""SL"", ""SERGEANT"", ""%s""]"`
+)
+
+func unitLine(name string) string {
+	return strings.Replace(testORBATUnit, "%s", name, 1)
+}
+
+// Malformed lines are skipped, parsing goes on from the next line.
+func TestParseSkipsMalformedLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		sep       string
+		wantUnits []string
+		wantErrs  map[int]error // line number to error
+	}{
+		{
+			name:      "CRLF",
+			lines:     []string{testORBATMeta, unitLine("Alpha"), unitLine("Bravo")},
+			sep:       "\r\n",
+			wantUnits: []string{"Alpha", "Bravo"},
+		},
+		{
+			name:      "line over the limit",
+			lines:     []string{testORBATMeta, unitLine(strings.Repeat("A", 200)), unitLine("Bravo")},
+			sep:       "\n",
+			wantUnits: []string{"Bravo"},
+			wantErrs:  map[int]error{2: parseerr.ErrTooLong},
+		},
+		{
+			name:      "line over the limit with CRLF",
+			lines:     []string{testORBATMeta, unitLine(strings.Repeat("A", 200)), unitLine("Bravo")},
+			sep:       "\r\n",
+			wantUnits: []string{"Bravo"},
+			wantErrs:  map[int]error{2: parseerr.ErrTooLong},
+		},
+		{
+			name:      "line truncated at EOF",
+			lines:     []string{testORBATMeta, unitLine("Alpha"), unitLine("Bravo")[:50]}, // cut after the role
+			sep:       "\n",
+			wantUnits: []string{"Alpha"},
+			wantErrs:  map[int]error{3: parseerr.ErrTruncated},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser(t.TempDir())
+			p.MaxLineLength = 120
+			p.Policy = parseerr.Skip
+
+			in := strings.Join(tt.lines, tt.sep)
+			if tt.wantErrs[len(tt.lines)] == nil {
+				in += tt.sep
+			}
+			content, err := p.Parse(context.Background(), "test.rpt", strings.NewReader(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(content.ORBATs) != 1 {
+				t.Fatalf("found %d ORBATs, want 1", len(content.ORBATs))
+			}
+			units := make([]string, 0)
+			for _, u := range content.ORBATs[0].Units() {
+				units = append(units, u.Name)
+			}
+			if !slices.Equal(units, tt.wantUnits) {
+				t.Errorf("units %q, want %q", units, tt.wantUnits)
+			}
+
+			if len(content.Skipped) != len(tt.wantErrs) {
+				t.Fatalf("skipped %v, want errors at lines %v", content.Skipped, tt.wantErrs)
+			}
+			for _, e := range content.Skipped {
+				if want := tt.wantErrs[e.Line]; !errors.Is(e, want) || e.File != "test.rpt" {
+					t.Errorf("skipped %v, want %v at line %d", e, want, e.Line)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAbortsOnLongLine(t *testing.T) {
+	p := NewParser(t.TempDir())
+	p.MaxLineLength = 120
+	p.Policy = parseerr.Abort
+	in := strings.Join([]string{testORBATMeta, unitLine(strings.Repeat("A", 200))}, "\n")
+	_, err := p.Parse(context.Background(), "test.rpt", strings.NewReader(in))
+	if !errors.Is(err, parseerr.ErrTooLong) {
+		t.Errorf("Parse error %v, want %v", err, parseerr.ErrTooLong)
+	}
+}
@@ -0,0 +1,186 @@
+package rpt
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/10Dozen/ts_aar_parser/linereader"
+)
+
+const (
+	DATE_FORMAT string = "2006-01-02"
+
+	CURRENT_TIME_PATTERN string = `^\s*Current time:\s+(\d{4})/(\d{2})/(\d{2})`
+	HEADER_LINES         int    = 50
+	READ_HEADER_MAX_LINE int    = 4096
+)
+
+var currentTimeRE = regexp.MustCompile(CURRENT_TIME_PATTERN)
+
+// RPT file and it's report date.
+type File struct {
+	Path    string
+	Entry   string // name of RPT file inside of zip archive at `Path`, empty for RPT file on disk
+	Date    string // report date, YYYY-MM-DD
+	ModTime time.Time
+
+	reader io.ReadCloser // content of RPT not stored on disk, see `FromReader`
+}
+
+// Filter for RPT files. Empty fields are ignored.
+type Selection struct {
+	Date  string    // exact report date
+	From  string    // first report date of the range, inclusive
+	To    string    // last report date of the range, inclusive
+	Since time.Time // files modified after this time
+}
+
+// Returns RPT file info. Report date is taken from the file name,
+// then from `Current time:` line of the RPT header and, as a last resort, from file modification time.
+// File may be gzip or zstd compressed, use `StatAll` for zip archives.
+func Stat(path string) (*File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{
+		Path:    path,
+		ModTime: info.ModTime(),
+	}
+	if err := file.detectDate(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Lists all RPT files in directory, including compressed ones and RPT files inside of zip archives.
+// Files are sorted by report date and name.
+func List(dir string) ([]*File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*File, 0)
+	for _, entry := range entries {
+		// -- Skip not RPT files
+		if entry.IsDir() || !(IsRPTName(entry.Name()) || IsArchiveName(entry.Name())) {
+			continue
+		}
+
+		found, err := StatAll(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, found...)
+	}
+
+	slices.SortFunc(files, func(a, b *File) int {
+		if c := strings.Compare(a.Date, b.Date); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Entry, b.Entry)
+	})
+	return files, nil
+}
+
+// Returns files matching the selection.
+func (s Selection) Filter(files []*File) []*File {
+	selected := make([]*File, 0, len(files))
+	for _, f := range files {
+		if s.Date != "" && f.Date != s.Date {
+			continue
+		}
+		if s.From != "" && f.Date < s.From {
+			continue
+		}
+		if s.To != "" && f.Date > s.To {
+			continue
+		}
+		if !s.Since.IsZero() && !f.ModTime.After(s.Since) {
+			continue
+		}
+		selected = append(selected, f)
+	}
+	return selected
+}
+
+// Checks that selection dates are valid.
+func (s Selection) Validate() error {
+	for _, d := range []string{s.Date, s.From, s.To} {
+		if d != "" && !IsDate(d) {
+			return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", d)
+		}
+	}
+	if s.From != "" && s.To != "" && s.From > s.To {
+		return fmt.Errorf("invalid date range %s..%s", s.From, s.To)
+	}
+	return nil
+}
+
+// Returns files of the latest report date.
+func Latest(files []*File) []*File {
+	latest := ""
+	for _, f := range files {
+		latest = max(latest, f.Date)
+	}
+	return Selection{Date: latest}.Filter(files)
+}
+
+// Groups files by report date. Returns dates in ascending order.
+func GroupByDate(files []*File) ([]string, map[string][]*File) {
+	dates := make([]string, 0)
+	groups := make(map[string][]*File)
+	for _, f := range files {
+		if _, ok := groups[f.Date]; !ok {
+			dates = append(dates, f.Date)
+		}
+		groups[f.Date] = append(groups[f.Date], f)
+	}
+	slices.Sort(dates)
+	return dates, groups
+}
+
+// Reads report date from `Current time: YYYY/MM/DD hh:mm:ss` line of the RPT header.
+// Returns empty string if there is no such line. Compressed files are supported.
+func DateFromContent(path string) (string, error) {
+	r, err := (&File{Path: path}).Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	return readHeaderDate(r)
+}
+
+func readHeaderDate(r io.Reader) (string, error) {
+	reader := linereader.New(r, READ_HEADER_MAX_LINE)
+	for i := 0; i < HEADER_LINES; i++ {
+		line, _, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if m := currentTimeRE.FindStringSubmatch(line); m != nil {
+			return fmt.Sprintf("%s-%s-%s", m[1], m[2], m[3]), nil
+		}
+	}
+	return "", nil
+}
+
+// Checks that given string is YYYY-MM-DD date.
+func IsDate(s string) bool {
+	_, err := time.Parse(DATE_FORMAT, s)
+	return err == nil
+}
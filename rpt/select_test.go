@@ -0,0 +1,117 @@
+package rpt
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelectionValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       Selection
+		wantErr bool
+	}{
+		{"empty", Selection{}, false},
+		{"date", Selection{Date: "2024-11-22"}, false},
+		{"range", Selection{From: "2024-11-01", To: "2024-11-22"}, false},
+		{"single day range", Selection{From: "2024-11-22", To: "2024-11-22"}, false},
+		{"open range from", Selection{From: "2024-11-01"}, false},
+		{"open range to", Selection{To: "2024-11-22"}, false},
+		{"reversed range", Selection{From: "2024-11-22", To: "2024-11-01"}, true},
+		{"invalid date", Selection{Date: "2024-13-01"}, true},
+		{"slashes", Selection{From: "2024/11/01"}, true},
+		{"short date", Selection{To: "2024-11-1"}, true},
+		{"range syntax in date", Selection{Date: "2024-11-01..2024-11-22"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.s.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSelectionFilter(t *testing.T) {
+	lastRun := time.Date(2024, 11, 21, 12, 0, 0, 0, time.UTC)
+	files := []*File{
+		{Path: "a.rpt", Date: "2024-10-31", ModTime: lastRun.Add(-time.Hour)},
+		{Path: "b.rpt", Date: "2024-11-01", ModTime: lastRun.Add(-time.Hour)},
+		{Path: "c.rpt", Date: "2024-11-21", ModTime: lastRun},
+		{Path: "d.rpt", Date: "2024-11-21", ModTime: lastRun.Add(time.Minute)},
+		{Path: "e.rpt", Date: "2024-11-22", ModTime: lastRun.Add(time.Hour)},
+	}
+	tests := []struct {
+		name string
+		s    Selection
+		want string
+	}{
+		{"empty", Selection{}, "abcde"},
+		{"date", Selection{Date: "2024-11-21"}, "cd"},
+		{"no files of date", Selection{Date: "2024-11-02"}, ""},
+		{"range inclusive", Selection{From: "2024-11-01", To: "2024-11-21"}, "bcd"},
+		{"from", Selection{From: "2024-11-21"}, "cde"},
+		{"to", Selection{To: "2024-11-01"}, "ab"},
+		{"since is exclusive", Selection{Since: lastRun}, "de"},
+		{"since and range", Selection{Since: lastRun, To: "2024-11-21"}, "d"},
+	}
+	for _, tt := range tests {
+		var got strings.Builder
+		for _, f := range tt.s.Filter(files) {
+			got.WriteString(strings.TrimSuffix(f.Path, RPT_SUFFIX))
+		}
+		if got.String() != tt.want {
+			t.Errorf("%s: selected %q, want %q", tt.name, got.String(), tt.want)
+		}
+	}
+
+	if got := Latest(files); len(got) != 1 || got[0].Path != "e.rpt" {
+		t.Errorf("Latest() = %v", got)
+	}
+	if got := Latest(nil); len(got) != 0 {
+		t.Errorf("Latest(nil) = %v", got)
+	}
+
+	dates, groups := GroupByDate([]*File{files[4], files[2], files[0], files[3]})
+	if !slices.Equal(dates, []string{"2024-10-31", "2024-11-21", "2024-11-22"}) {
+		t.Errorf("GroupByDate() dates %v", dates)
+	}
+	if g := groups["2024-11-21"]; len(g) != 2 || g[0] != files[2] || g[1] != files[3] {
+		t.Errorf("GroupByDate() group %v", g)
+	}
+}
+
+func TestDateFromFilename(t *testing.T) {
+	tests := map[string]string{
+		"arma3server_x64_2024-11-21_22-00-00.rpt":    "2024-11-21",
+		"ARMA3SERVER_X64_2024-11-21_22-00-00.RPT.gz": "2024-11-21",
+		"arma3server_2024-11-21_22-00-00.rpt":        "2024-11-21",
+		"server.rpt":                                 "",
+		"arma3server_x64.rpt":                        "",
+	}
+	for name, want := range tests {
+		if got := DateFromFilename(name); got != want {
+			t.Errorf("DateFromFilename(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestReadHeaderDate(t *testing.T) {
+	long := strings.Repeat("x", READ_HEADER_MAX_LINE*2)
+	tests := []struct {
+		name, in, want string
+	}{
+		{"header", testRPT, "2024-11-21"},
+		{"CRLF", strings.ReplaceAll(testRPT, "\n", "\r\n"), "2024-11-21"},
+		{"after long line", long + "\n" + testRPT, "2024-11-21"},
+		{"no header", "22:22:22 line\n", ""},
+		{"too far", strings.Repeat("line\n", HEADER_LINES) + testRPT, ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		got, err := readHeaderDate(strings.NewReader(tt.in))
+		if err != nil || got != tt.want {
+			t.Errorf("%s: readHeaderDate() = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/10Dozen/ts_aar_parser/export"
+)
+
+const (
+	LAST_RUN_FILENAME         string = "last_run.json"
+	LAST_RUN_PROFILE_FILENAME        = "last_run.%s.json"
+
+	ATTENDANCE_FILENAME         string = "attendance.jsonl"
+	ATTENDANCE_PROFILE_FILENAME        = "attendance.%s.jsonl"
+)
+
+// State saved between runs.
+type LastRun struct {
+	Time time.Time
+}
+
+// Last run state is kept in AAR directory, as directory of the executable may be read-only.
+// Each profile has it's own last run state.
+func lastRunPath() string {
+	if configuration.profile != "" {
+		filename := fmt.Sprintf(LAST_RUN_PROFILE_FILENAME, export.SafeFilename(configuration.profile))
+		return filepath.Join(configuration.AARDirectory, filename)
+	}
+	return filepath.Join(configuration.AARDirectory, LAST_RUN_FILENAME)
+}
+
+// Returns time of the last successful conversion, zero time if there were none.
+func loadLastRun() (time.Time, error) {
+	content, err := os.ReadFile(lastRunPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	state := &LastRun{}
+	if err := json.Unmarshal(content, state); err != nil {
+		return time.Time{}, err
+	}
+	return state.Time, nil
+}
+
+// Saves time of successful conversion for `--since-last-run`.
+func saveLastRun(t time.Time) error {
+	content, err := json.MarshalIndent(&LastRun{Time: t}, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(lastRunPath(), content, 0644)
+}
+
+// Attendance ledger from config or in AAR directory, as directory of the executable may be read-only.
+// Each profile has it's own ledger.
+func attendancePath() string {
+	if configuration.AttendanceFile != "" {
+		return configuration.AttendanceFile
+	}
+	if configuration.profile != "" {
+		filename := fmt.Sprintf(ATTENDANCE_PROFILE_FILENAME, export.SafeFilename(configuration.profile))
+		return filepath.Join(configuration.AARDirectory, filename)
+	}
+	return filepath.Join(configuration.AARDirectory, ATTENDANCE_FILENAME)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/10Dozen/ts_aar_parser/aar"
+	"github.com/10Dozen/ts_aar_parser/export"
+	"github.com/10Dozen/ts_aar_parser/orbat"
+	"github.com/10Dozen/ts_aar_parser/parseerr"
+	"github.com/10Dozen/ts_aar_parser/rpt"
+	"github.com/10Dozen/ts_aar_parser/watch"
+)
+
+// Runs `watch` command: follows the latest RPT file and exports AARs and ORBATs as soon as they are complete.
+// Stops on Ctrl+C, AAR that is not complete yet is not exported.
+func runWatch(ctx context.Context, opts *CLIOptions) int {
+	if err := loadConfiguration(opts, true); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		return EXIT_FAILURE
+	}
+	if err := checkRptDirectory(); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		return EXIT_FAILURE
+	}
+	if err := prepareOutputDirectories(true, true); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		return EXIT_FAILURE
+	}
+	if !opts.InMemory {
+		if err := prepareTmpDirectory(); err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+			return EXIT_FAILURE
+		}
+	}
+	sweepTempFiles()
+
+	policy := parseerr.Skip
+	if opts.Strict {
+		policy = parseerr.Abort
+	}
+
+	w := watch.NewWatcher(configuration.RptDirectory, configuration.TmpDirectory)
+	w.InMemory = opts.InMemory
+	w.Policy = policy
+	w.MaxLineLength = opts.MaxLine
+	w.Poll = opts.Poll
+	w.Settle = opts.Settle
+
+	w.OnFile = func(file *rpt.File) {
+		fmt.Printf("Отслеживается RPT файл: %s (%s)\n", filepath.Base(file.Path), file.Date)
+	}
+	w.OnSkipped = printSkipped
+	w.OnORBAT = func(ctx context.Context, date string, orbats []*orbat.ORBAT) error {
+		rptContent := &rpt.Content{Date: date, ORBATs: orbats}
+		if err := exportOrbat(ctx, rptContent, opts.OrbatOrder); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		}
+		return ctx.Err()
+	}
+	w.OnAAR = func(ctx context.Context, a *aar.AAR) error {
+		if err := exportWatchedAAR(ctx, a, policy, opts.Duplicates); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Ошибка: AAR %s: %v\n", a.Name, err)
+		}
+		return ctx.Err()
+	}
+
+	fmt.Printf("Наблюдение за %s, Ctrl+C для остановки.\n", configuration.RptDirectory)
+	err := w.Run(ctx)
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		fmt.Println("\nНаблюдение остановлено.")
+		return EXIT_OK
+	}
+	fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+	return EXIT_FAILURE
+}
+
+func exportWatchedAAR(ctx context.Context, a *aar.AAR, policy parseerr.Policy, duplicates export.Duplicates) error {
+	converted, skipped, err := a.Parse(ctx, policy)
+	printSkipped(skipped)
+	if err != nil {
+		return err
+	}
+
+	writer, err := export.NewAARWriter(configuration.AARDirectory, a.Date, duplicates, configuration.naming)
+	if err != nil {
+		return err
+	}
+	if err := writer.Write(ctx, converted); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if len(writer.Skipped) > 0 {
+		fmt.Printf("AAR %s уже экспортирован в %s, пропущен.\n", a.Name, writer.Skipped[0].Link)
+		return nil
+	}
+	fmt.Printf("AAR %s ▸ %s ▸ %s экспортирован.\n", a.TimeLabel, a.Name, a.Terrain)
+	printORBATLinks([]*orbatLink{newORBATLink(converted)})
+	printFriendlyFire(writer.FriendlyFire)
+	return nil
+}
@@ -0,0 +1,350 @@
+// Package watch follows the active RPT file and reports AARs and ORBATs as soon as they are complete.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/10Dozen/ts_aar_parser/aar"
+	"github.com/10Dozen/ts_aar_parser/linereader"
+	"github.com/10Dozen/ts_aar_parser/orbat"
+	"github.com/10Dozen/ts_aar_parser/parseerr"
+	"github.com/10Dozen/ts_aar_parser/rpt"
+)
+
+const (
+	DEFAULT_POLL   time.Duration = 2 * time.Second
+	DEFAULT_SETTLE time.Duration = 30 * time.Second
+)
+
+var (
+	aarPatterns   = aar.NewPatterns()
+	orbatPatterns = orbat.NewPatterns()
+)
+
+// Follows the latest RPT file in `Dir`. When Arma starts a new RPT file, the rest of the previous one is read
+// and the new file is followed from the start. Truncated file is re-read from the start.
+//
+// AAR is complete when the next AAR header is found or no AAR lines were written for `Settle` time,
+// other lines of the server log don't delay it.
+// ORBAT is complete when a non-ORBAT line follows it or no lines were written for `Settle` time.
+// Complete blocks are passed to callbacks, error returned by a callback stops watching.
+type Watcher struct {
+	Dir           string
+	TmpDir        string          // directory for AAR temporary files
+	InMemory      bool            // convert AARs while reading RPT instead of spooling them to `TmpDir`
+	Policy        parseerr.Policy // what to do with malformed lines
+	MaxLineLength int             // lines longer than this are reported and skipped, 0 - no limit
+	Poll          time.Duration   // how often directory and file are checked for changes
+	Settle        time.Duration   // time without AAR and ORBAT lines after which the last AAR or ORBAT is complete
+
+	OnFile    func(file *rpt.File)                                                // new file is followed
+	OnAAR     func(ctx context.Context, a *aar.AAR) error                         // AAR temporary file is owned by callback
+	OnORBAT   func(ctx context.Context, date string, orbats []*orbat.ORBAT) error // all ORBATs of the date found so far
+	OnSkipped func(skipped parseerr.List)                                         // malformed lines, with `Skip` policy only
+
+	orbats map[string][]*orbat.ORBAT // ORBATs of followed files by report date, without current file
+}
+
+// Followed RPT file.
+type source struct {
+	file *rpt.File
+	f    *os.File
+	tail *linereader.Tail
+	read int64 // bytes read, lower bound of the read position used to detect truncation
+	num  int
+
+	aars   *aar.Handler
+	orbats *orbat.Handler
+
+	reportedAARs int       // number of AARs passed to `OnAAR`
+	inORBAT      bool      // last line belongs to ORBAT not passed to `OnORBAT` yet
+	lastLine     time.Time // time of the last AAR or ORBAT line
+	settled      bool      // pending blocks are reported after the last AAR or ORBAT line
+}
+
+func NewWatcher(dir, tmpDir string) *Watcher {
+	return &Watcher{
+		Dir:           dir,
+		TmpDir:        tmpDir,
+		MaxLineLength: rpt.DEFAULT_MAX_LINE_LENGTH,
+		Poll:          DEFAULT_POLL,
+		Settle:        DEFAULT_SETTLE,
+	}
+}
+
+// Watches RPT directory until `ctx` is cancelled or an error occurs.
+// Temporary files of incomplete AARs are removed on return.
+func (w *Watcher) Run(ctx context.Context) error {
+	w.orbats = make(map[string][]*orbat.ORBAT)
+
+	var src *source
+	defer func() {
+		if src != nil {
+			src.close()
+		}
+	}()
+
+	ticker := time.NewTicker(w.Poll)
+	defer ticker.Stop()
+	for {
+		latest, err := latestRPT(w.Dir)
+		if err != nil {
+			return err
+		}
+
+		// -- New RPT file: finish the previous one
+		if latest != "" && (src == nil || latest != src.file.Path) {
+			if src != nil {
+				if err := w.finish(ctx, src); err != nil {
+					return err
+				}
+			}
+			if src, err = w.open(latest); err != nil {
+				return err
+			}
+		}
+
+		if src != nil {
+			if err := w.follow(ctx, src); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Watcher) open(path string) (*source, error) {
+	file, err := rpt.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.OnFile != nil {
+		w.OnFile(file)
+	}
+	aars := aar.NewHandler(w.TmpDir)
+	aars.InMemory = w.InMemory
+	return &source{
+		file:     file,
+		f:        f,
+		tail:     linereader.NewTail(f, w.MaxLineLength),
+		aars:     aars,
+		orbats:   orbat.NewHandler(),
+		lastLine: time.Now(),
+	}, nil
+}
+
+// Reads new lines of the file and reports complete blocks.
+func (w *Watcher) follow(ctx context.Context, src *source) error {
+	// -- File was truncated or recreated: read it from the start
+	info, err := os.Stat(src.file.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil && info.Size() < src.read {
+		path, date := src.file.Path, src.file.Date
+		found := len(w.orbats[date])
+		if err := w.finish(ctx, src); err != nil {
+			return err
+		}
+		// -- ORBATs of the old content are found again if they are still in the file
+		w.orbats[date] = w.orbats[date][:found]
+		reopened, err := w.open(path)
+		if err != nil {
+			return err
+		}
+		*src = *reopened
+	}
+
+	if err := w.read(ctx, src); err != nil {
+		return err
+	}
+
+	if !src.settled && time.Since(src.lastLine) >= w.Settle {
+		src.settled = true
+		return w.report(ctx, src, true)
+	}
+	return nil
+}
+
+// Reads the rest of the file, reports all pending blocks and closes the file.
+func (w *Watcher) finish(ctx context.Context, src *source) error {
+	if err := w.read(ctx, src); err != nil {
+		return err
+	}
+	if line, truncated, ok := src.tail.Rest(); ok {
+		if err := w.handleLine(ctx, src, line, truncated); err != nil {
+			return err
+		}
+	}
+	if err := w.report(ctx, src, true); err != nil {
+		return err
+	}
+
+	date := src.file.Date
+	w.orbats[date] = append(w.orbats[date], src.orbats.ORBATs()...)
+	src.close()
+	return nil
+}
+
+func (w *Watcher) read(ctx context.Context, src *source) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line, truncated, err := src.tail.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", src.file.Path, src.num+1, err)
+		}
+		src.read += int64(len(line)) + 1
+		if err := w.handleLine(ctx, src, line, truncated); err != nil {
+			return err
+		}
+	}
+}
+
+func (w *Watcher) handleLine(ctx context.Context, src *source, line string, truncated bool) error {
+	src.num++
+
+	// -- ORBAT block ends with the first non-ORBAT line
+	isORBAT := orbatPatterns.Test.MatchString(line)
+	if isORBAT || aarPatterns.Test.MatchString(line) {
+		src.lastLine = time.Now()
+		src.settled = false
+	}
+	if src.inORBAT && !isORBAT {
+		if err := w.reportORBATs(ctx, src); err != nil {
+			return err
+		}
+	}
+	src.inORBAT = isORBAT
+
+	errs := []error{parseerr.New(src.num, line, parseerr.ErrTooLong)}
+	if !truncated {
+		errs = []error{
+			src.orbats.ParseLine(src.num, line),
+			src.aars.ParseLine(src.num, line),
+		}
+	}
+
+	var skipped parseerr.List
+	for _, err := range errs {
+		var lineErr *parseerr.Error
+		if errors.As(err, &lineErr) {
+			lineErr.File = filepath.Base(src.file.Path)
+		}
+		if err = skipped.Handle(err, w.Policy); err != nil {
+			return err
+		}
+	}
+	if len(skipped) > 0 && w.OnSkipped != nil {
+		w.OnSkipped(skipped)
+	}
+
+	return w.report(ctx, src, false)
+}
+
+// Reports complete AARs and, if `all` is set, the last AAR and ORBAT as well.
+func (w *Watcher) report(ctx context.Context, src *source, all bool) error {
+	if all {
+		if err := src.aars.Close(); err != nil {
+			return err
+		}
+		if src.inORBAT {
+			src.inORBAT = false
+			if err := w.reportORBATs(ctx, src); err != nil {
+				return err
+			}
+		}
+	}
+
+	// -- AAR is complete once the next AAR started
+	aars := src.aars.AARs()
+	complete := len(aars) - 1
+	if all {
+		complete = len(aars)
+	}
+	if src.reportedAARs < complete {
+		name := filepath.Base(src.file.Path)
+		for _, a := range aars {
+			a.Date, a.Source = src.file.Date, name
+		}
+		for _, o := range src.orbats.ORBATs() {
+			o.Source = name
+		}
+		aar.LinkORBATs(aars, append(append([]*orbat.ORBAT{}, w.orbats[src.file.Date]...), src.orbats.ORBATs()...))
+	}
+	for ; src.reportedAARs < complete; src.reportedAARs++ {
+		a := aars[src.reportedAARs]
+		if w.OnAAR == nil {
+			a.Discard()
+			continue
+		}
+		if err := w.OnAAR(ctx, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) reportORBATs(ctx context.Context, src *source) error {
+	if w.OnORBAT == nil {
+		return nil
+	}
+	date := src.file.Date
+	orbats := append(append([]*orbat.ORBAT{}, w.orbats[date]...), src.orbats.ORBATs()...)
+	return w.OnORBAT(ctx, date, orbats)
+}
+
+// Closes the file and removes temporary files of AARs that were not reported.
+func (src *source) close() {
+	src.aars.Close()
+	aar.Clear(src.aars.AARs()[src.reportedAARs:])
+	src.f.Close()
+}
+
+// Returns path of the most recently modified RPT file in `dir`, empty string if there are none.
+func latestRPT(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		latest  string
+		modTime time.Time
+	)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(strings.ToLower(entry.Name()), rpt.RPT_SUFFIX) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if latest == "" || info.ModTime().After(modTime) {
+			latest, modTime = filepath.Join(dir, entry.Name()), info.ModTime()
+		}
+	}
+	return latest, nil
+}
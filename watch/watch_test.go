@@ -0,0 +1,70 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/10Dozen/ts_aar_parser/orbat"
+)
+
+var testORBAT = []string{
+	`22:22:22 "[tS_ORBAT] Meta: CO10 Test"`,
+	`22:22:22 "[tS_ORBAT] [""BLUFOR"", ""1'1"", ""SL"", ""SERGEANT"", ""Alpha""]"`,
+	`22:22:23 Server log line`,
+}
+
+// Truncated file is read from the start, ORBATs found before truncation are not reported twice.
+func TestWatcherTruncatedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "arma3server_x64_2024-11-22_22-00-00.rpt")
+	write := func(lines []string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(append(append([]string{}, testORBAT...), `22:22:24 Mission ended, log line written before truncation`))
+
+	w := NewWatcher(dir, t.TempDir())
+	w.Poll, w.Settle = 10*time.Millisecond, time.Hour
+	reported := make(chan int, 16)
+	w.OnORBAT = func(ctx context.Context, date string, orbats []*orbat.ORBAT) error {
+		reported <- len(orbats)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Run: %v", err)
+		}
+	}()
+
+	next := func() int {
+		t.Helper()
+		select {
+		case n := <-reported:
+			return n
+		case <-time.After(5 * time.Second):
+			t.Fatal("ORBAT is not reported")
+			return 0
+		}
+	}
+	if n := next(); n != 1 {
+		t.Fatalf("%d ORBATs reported, want 1", n)
+	}
+
+	// -- Shorter content with the same ORBAT
+	write(testORBAT)
+	if n := next(); n != 1 {
+		t.Errorf("%d ORBATs reported after truncation, want 1", n)
+	}
+}